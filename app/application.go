@@ -1,16 +1,136 @@
 package app
 
 import (
+	"context"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/annazhao/bookstore_users_api/config"
+	healthController "github.com/annazhao/bookstore_users_api/controllers/health"
+	"github.com/annazhao/bookstore_users_api/controllers/users"
+	usersdb "github.com/annazhao/bookstore_users_api/datasources/mysql/users_db"
+	"github.com/annazhao/bookstore_users_api/health"
 	"github.com/annazhao/bookstore_users_api/logger"
+	"github.com/annazhao/bookstore_users_api/metrics"
+	"github.com/annazhao/bookstore_users_api/services"
+	"github.com/annazhao/bookstore_users_api/tracing"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
-var router = gin.Default()
+// gin.New instead of gin.Default: requests are logged by our zap access log, not by the text logger of gin
+var router = gin.New()
+
+// StartApplication gives the configuration to every package that needs it, then runs the http server
+// until SIGINT or SIGTERM: in-flight requests are drained, then the database and the logger are closed
+func StartApplication(cfg *config.Config) error {
+	if err := logger.Init(cfg.Log); err != nil {
+		return err
+	}
+	defer logger.Sync()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Init(ctx, cfg.Tracing)
+	if err != nil {
+		return err
+	}
+	// the spans still buffered are sent before the application stops
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Error("error when trying to flush traces", err)
+		}
+	}()
+
+	// the pool is created now, but the connection is retried in the background,
+	// so a database which is not up yet does not stop the application from starting
+	if err := usersdb.Open(cfg.Database); err != nil {
+		return err
+	}
+	if err := metrics.RegisterDatabase(cfg.Database.Schema, usersdb.Client); err != nil {
+		return err
+	}
+	defer func() {
+		if err := usersdb.Close(); err != nil {
+			logger.Error("error when trying to close database", err)
+		}
+	}()
+	go func() {
+		if err := usersdb.Connect(ctx, cfg.Database); err != nil {
+			logger.Error("database is not available", err)
+		}
+	}()
+	users.Configure(cfg.Users)
+	services.Configure(cfg.Users)
+	healthController.Configure(cfg.Server.HealthTimeout)
+
+	// /readyz only answers ok when every registered check is up
+	health.Register("connecting", usersdb.CheckConnected)
+	health.Register("database", usersdb.CheckConnection)
+	health.Register("migrations", usersdb.CheckMigrations)
+	health.Register("draining", health.CheckNotDraining)
+
+	mapUrls(cfg)
+
+	server := &http.Server{
+		Addr:              cfg.Server.Address,
+		Handler:           router,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	metricsServer := &http.Server{
+		Addr:              cfg.Server.MetricsAddress,
+		Handler:           metricsHandler(),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+	}
+
+	serverErr := make(chan error, 2)
+	go func() {
+		logger.Info("about to serve the metrics", zap.String("address", cfg.Server.MetricsAddress))
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			serverErr <- err
+		}
+	}()
+	go func() {
+		// logger.Log.Info("about to start the application...")
+		logger.Info("about to start the application...", zap.String("address", cfg.Server.Address))
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case err := <-serverErr:
+		metricsServer.Close()
+		server.Close()
+		return err
+	case <-ctx.Done():
+	}
 
-func StartApplication() {
-	mapUrls()
+	// first tell the load balancer we are going away, then stop accepting requests and wait for in-flight ones
+	health.SetDraining()
+	logger.Info("draining", zap.Duration("delay", cfg.Server.DrainDelay))
+	time.Sleep(cfg.Server.DrainDelay)
 
-	// logger.Log.Info("about to start the application...")
-	logger.Info("about to start the application...")
-	router.Run(":8080")
+	logger.Info("shutting down, waiting for in-flight requests", zap.Duration("timeout", cfg.Server.ShutdownTimeout))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("error when trying to shut down the server gracefully", err)
+		return err
+	}
+	// metrics are served until the end, so the last requests are still scraped
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("error when trying to shut down the metrics server", err)
+	}
+	logger.Info("server stopped")
+	return nil
 }
@@ -1,17 +1,60 @@
 package app
 
 import (
+	"net/http"
+
+	"github.com/annazhao/bookstore_users_api/config"
+	"github.com/annazhao/bookstore_users_api/controllers/admin"
+	"github.com/annazhao/bookstore_users_api/controllers/audits"
+	"github.com/annazhao/bookstore_users_api/controllers/database"
+	"github.com/annazhao/bookstore_users_api/controllers/errorcodes"
+	"github.com/annazhao/bookstore_users_api/controllers/health"
 	"github.com/annazhao/bookstore_users_api/controllers/ping"
 	"github.com/annazhao/bookstore_users_api/controllers/users"
+	"github.com/annazhao/bookstore_users_api/middlewares"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func mapUrls() {
+func mapUrls(cfg *config.Config) {
+	// the request id comes first so the access log and the errors of every other middleware have it,
+	// recovery comes last so a panic is still counted, traced and logged as a 500
+	router.Use(middlewares.RequestID(), middlewares.AccessLog(cfg.Log.Access), middlewares.Metrics(), middlewares.Tracing(), middlewares.Recovery())
+
 	router.GET("/ping", ping.Ping)
-	router.POST("/users", users.Create)
+	router.GET("/healthz", health.Healthz)
+	router.GET("/readyz", health.Readyz)
+	router.GET("/errors/codes", errorcodes.List)
+	router.POST("/users", middlewares.Idempotency(), users.Create)
 	router.GET("/users/:user_id", users.Get)
+	router.GET("/users/:user_id/versions", users.Versions)
 	router.PUT("/users/:user_id", users.Update)
 	router.PATCH("/users/:user_id", users.Update)
 	router.DELETE("/users/:user_id", users.Delete)
+	router.POST("/users/:user_id/activate", middlewares.Idempotency(), users.Activate)
+	router.POST("/users/:user_id/suspend", middlewares.Idempotency(), users.Suspend)
+	router.POST("/users/:user_id/reactivate", middlewares.Idempotency(), users.Reactivate)
+	router.POST("/users/:user_id/lock", middlewares.Idempotency(), users.Lock)
+	router.POST("/users/:user_id/unlock", middlewares.Idempotency(), users.Unlock)
 	router.GET("/internal/users/search", users.Search)
-	router.POST("/users/login", users.Login)
+	router.POST("/users/login", middlewares.Idempotency(), users.Login)
+
+	// without a token the admin endpoints are not available at all
+	if cfg.Admin.Token != "" {
+		adminRoutes := router.Group("/admin", middlewares.AdminAuth(cfg.Admin.Token))
+		adminRoutes.GET("/log/level", admin.GetLogLevel)
+		adminRoutes.PUT("/log/level", admin.SetLogLevel)
+		// the audit trail has the before and after data of every user, so it is only for admins
+		adminRoutes.GET("/audits", audits.Search)
+		// the pool stats tell how loaded the database is, which is not for the public either
+		adminRoutes.GET("/database/stats", database.Stats)
+		// /readyz only says which checks are down, this one also says why
+		adminRoutes.GET("/readyz", health.ReadyzDetails)
+	}
+}
+
+// metricsHandler serves /metrics on its own listener (server.metrics_address), never on the public router
+func metricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return mux
 }
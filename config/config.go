@@ -0,0 +1,263 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Config holds every setting of the users api, it is loaded once at startup by Load
+// and then given to the packages that need it (datasource, logger, http server...)
+type Config struct {
+	Server   ServerConfig   `yaml:"server" toml:"server"`
+	Database DatabaseConfig `yaml:"database" toml:"database"`
+	Log      LogConfig      `yaml:"log" toml:"log"`
+	Users    UsersConfig    `yaml:"users" toml:"users"`
+	Tracing  TracingConfig  `yaml:"tracing" toml:"tracing"`
+	Admin    AdminConfig    `yaml:"admin" toml:"admin"`
+}
+
+// ServerConfig is the configuration of the http server,
+// ShutdownTimeout is how long in-flight requests have to finish once we are asked to stop,
+// DrainDelay is how long /readyz reports draining before we stop accepting requests, so load balancers can notice.
+// MetricsAddress is where /metrics is served, it is another listener so that port is never exposed with the api
+type ServerConfig struct {
+	Address           string        `yaml:"address" toml:"address"`
+	MetricsAddress    string        `yaml:"metrics_address" toml:"metrics_address"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	DrainDelay        time.Duration `yaml:"drain_delay" toml:"drain_delay"`
+	HealthTimeout     time.Duration `yaml:"health_timeout" toml:"health_timeout"`
+}
+
+// DatabaseConfig is the configuration of the users mysql database,
+// PasswordFile can be used instead of Password to read the secret from a file (e.g. a mounted secret).
+// The connection is retried with an exponential backoff starting at RetryBackoff and capped at RetryMaxBackoff,
+// ConnectAttempts of 0 means we keep trying until the application stops.
+// The Max* settings tune the connection pool, MaxOpenConns of 0 means no limit
+type DatabaseConfig struct {
+	Username        string        `yaml:"username" toml:"username"`
+	Password        string        `yaml:"password" toml:"password"`
+	PasswordFile    string        `yaml:"password_file" toml:"password_file"`
+	Host            string        `yaml:"host" toml:"host"`
+	Schema          string        `yaml:"schema" toml:"schema"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" toml:"connect_timeout"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	ConnectAttempts int           `yaml:"connect_attempts" toml:"connect_attempts"`
+	RetryBackoff    time.Duration `yaml:"retry_backoff" toml:"retry_backoff"`
+	RetryMaxBackoff time.Duration `yaml:"retry_max_backoff" toml:"retry_max_backoff"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+}
+
+// LogConfig is the configuration of the zap logger and of the access log,
+// Encoding is json or console, each output is stdout, stderr or the path of a file rotated with File
+type LogConfig struct {
+	Level    string    `yaml:"level" toml:"level"`
+	Encoding string    `yaml:"encoding" toml:"encoding"`
+	Outputs  []string  `yaml:"outputs" toml:"outputs"`
+	File     LogFile   `yaml:"file" toml:"file"`
+	Access   AccessLog `yaml:"access" toml:"access"`
+}
+
+// LogFile is how the log files are rotated: a file is rotated once it reaches MaxSizeMB,
+// and the rotated files are removed after MaxAgeDays or when there are more than MaxBackups (0 keeps them all)
+type LogFile struct {
+	MaxSizeMB  int  `yaml:"max_size_mb" toml:"max_size_mb"`
+	MaxBackups int  `yaml:"max_backups" toml:"max_backups"`
+	MaxAgeDays int  `yaml:"max_age_days" toml:"max_age_days"`
+	Compress   bool `yaml:"compress" toml:"compress"`
+}
+
+// AccessLog is the configuration of the access log: SampleRatio of the successful requests are logged
+// (requests failing with 4xx or 5xx are always logged), requests to ExcludePaths are never logged
+type AccessLog struct {
+	SampleRatio  float64  `yaml:"sample_ratio" toml:"sample_ratio"`
+	ExcludePaths []string `yaml:"exclude_paths" toml:"exclude_paths"`
+}
+
+// UsersConfig is the configuration of the users endpoints
+type UsersConfig struct {
+	RequireIfMatch   bool          `yaml:"require_if_match" toml:"require_if_match"`
+	IdempotencyTTL   time.Duration `yaml:"idempotency_ttl" toml:"idempotency_ttl"`
+	IdempotencyLease time.Duration `yaml:"idempotency_lease" toml:"idempotency_lease"`
+}
+
+// TracingConfig is the configuration of the OpenTelemetry tracing, Exporter is one of:
+// none (spans are not recorded, traceparent is still propagated), otlp (sent to Endpoint with OTLP over http),
+// stdout (printed, or written to File when set, for local use)
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter"`
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"`
+	Insecure    bool    `yaml:"insecure" toml:"insecure"`
+	File        string  `yaml:"file" toml:"file"`
+	ServiceName string  `yaml:"service_name" toml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// AdminConfig is the configuration of the /admin endpoints, they are only available when Token is set,
+// TokenFile can be used instead of Token to read the secret from a file
+type AdminConfig struct {
+	Token     string `yaml:"token" toml:"token"`
+	TokenFile string `yaml:"token_file" toml:"token_file"`
+}
+
+const (
+	LogEncodingJSON    = "json"
+	LogEncodingConsole = "console"
+)
+
+const (
+	TracingExporterNone   = "none"
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+)
+
+// logLevels are the levels zap understands
+var logLevels = map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+
+// Default returns the configuration used when nothing else is set
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Address:           ":8080",
+			MetricsAddress:    ":9090",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   20 * time.Second,
+			HealthTimeout:     2 * time.Second,
+		},
+		Database: DatabaseConfig{
+			ConnectTimeout:  5 * time.Second,
+			ReadTimeout:     30 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			RetryBackoff:    500 * time.Millisecond,
+			RetryMaxBackoff: 30 * time.Second,
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 5 * time.Minute,
+			ConnMaxIdleTime: time.Minute,
+		},
+		Log: LogConfig{
+			Level:    "info",
+			Encoding: LogEncodingJSON,
+			Outputs:  []string{"stdout"},
+			File: LogFile{
+				MaxSizeMB:  100,
+				MaxBackups: 10,
+				MaxAgeDays: 30,
+			},
+			Access: AccessLog{
+				SampleRatio:  1,
+				ExcludePaths: []string{"/healthz", "/readyz", "/ping"},
+			},
+		},
+		Users: UsersConfig{
+			IdempotencyTTL:   24 * time.Hour,
+			IdempotencyLease: time.Minute,
+		},
+		Tracing: TracingConfig{
+			Exporter:    TracingExporterNone,
+			ServiceName: "users_api",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Validate method is used at startup to stop early with every problem of the configuration
+func (cfg *Config) Validate() error {
+	problems := make([]string, 0)
+	if cfg.Server.Address == "" {
+		problems = append(problems, "server.address is required")
+	}
+	if cfg.Server.MetricsAddress == "" || cfg.Server.MetricsAddress == cfg.Server.Address {
+		problems = append(problems, "server.metrics_address is required and should not be server.address")
+	}
+	if cfg.Server.ReadHeaderTimeout <= 0 || cfg.Server.ReadTimeout <= 0 || cfg.Server.WriteTimeout <= 0 || cfg.Server.IdleTimeout <= 0 {
+		problems = append(problems, "server timeouts should be positive")
+	}
+	if cfg.Server.ShutdownTimeout <= 0 {
+		problems = append(problems, "server.shutdown_timeout should be positive")
+	}
+	if cfg.Server.DrainDelay < 0 {
+		problems = append(problems, "server.drain_delay should not be negative")
+	}
+	if cfg.Server.HealthTimeout <= 0 {
+		problems = append(problems, "server.health_timeout should be positive")
+	}
+	if cfg.Database.Username == "" {
+		problems = append(problems, "database.username is required")
+	}
+	if cfg.Database.Host == "" {
+		problems = append(problems, "database.host is required")
+	}
+	if cfg.Database.Schema == "" {
+		problems = append(problems, "database.schema is required")
+	}
+	if cfg.Database.ConnectTimeout <= 0 || cfg.Database.ReadTimeout <= 0 || cfg.Database.WriteTimeout <= 0 {
+		problems = append(problems, "database timeouts should be positive")
+	}
+	if cfg.Database.ConnectAttempts < 0 {
+		problems = append(problems, "database.connect_attempts should not be negative")
+	}
+	if cfg.Database.RetryBackoff <= 0 || cfg.Database.RetryMaxBackoff < cfg.Database.RetryBackoff {
+		problems = append(problems, "database.retry_backoff should be positive and not above database.retry_max_backoff")
+	}
+	if cfg.Database.MaxOpenConns < 0 || cfg.Database.MaxIdleConns < 0 {
+		problems = append(problems, "database.max_open_conns and database.max_idle_conns should not be negative")
+	}
+	if cfg.Database.MaxOpenConns > 0 && cfg.Database.MaxIdleConns > cfg.Database.MaxOpenConns {
+		problems = append(problems, "database.max_idle_conns should not be above database.max_open_conns")
+	}
+	if cfg.Database.ConnMaxLifetime < 0 || cfg.Database.ConnMaxIdleTime < 0 {
+		problems = append(problems, "database.conn_max_lifetime and database.conn_max_idle_time should not be negative")
+	}
+	if !logLevels[cfg.Log.Level] {
+		problems = append(problems, fmt.Sprintf("log.level %q should be one of debug, info, warn, error", cfg.Log.Level))
+	}
+	if cfg.Log.Encoding != LogEncodingJSON && cfg.Log.Encoding != LogEncodingConsole {
+		problems = append(problems, fmt.Sprintf("log.encoding %q should be json or console", cfg.Log.Encoding))
+	}
+	if cfg.Log.File.MaxSizeMB <= 0 {
+		problems = append(problems, "log.file.max_size_mb should be positive")
+	}
+	if cfg.Log.File.MaxBackups < 0 || cfg.Log.File.MaxAgeDays < 0 {
+		problems = append(problems, "log.file.max_backups and log.file.max_age_days should not be negative")
+	}
+	if len(cfg.Log.Outputs) == 0 {
+		problems = append(problems, "log.outputs should have at least one output")
+	}
+	if cfg.Log.Access.SampleRatio < 0 || cfg.Log.Access.SampleRatio > 1 {
+		problems = append(problems, "log.access.sample_ratio should be between 0 and 1")
+	}
+	if cfg.Users.IdempotencyTTL <= 0 {
+		problems = append(problems, "users.idempotency_ttl should be positive")
+	}
+	if cfg.Users.IdempotencyLease <= 0 || cfg.Users.IdempotencyLease > cfg.Users.IdempotencyTTL {
+		problems = append(problems, "users.idempotency_lease should be positive and not above users.idempotency_ttl")
+	}
+	switch cfg.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterOTLP, TracingExporterStdout:
+	default:
+		problems = append(problems, fmt.Sprintf("tracing.exporter %q should be one of none, otlp, stdout", cfg.Tracing.Exporter))
+	}
+	if cfg.Tracing.ServiceName == "" {
+		problems = append(problems, "tracing.service_name is required")
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		problems = append(problems, "tracing.sample_ratio should be between 0 and 1")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// setting is one value that can be given as a flag (e.g. -database.host) or an environment variable,
+// the database variables keep their historical names so existing environments still work
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	apply func(cfg *Config, value string) error
+}
+
+var settings = []setting{
+	{"server.address", "users_server_address", "address the http server listens on", func(cfg *Config, value string) error {
+		cfg.Server.Address = value
+		return nil
+	}},
+	{"server.metrics_address", "users_server_metrics_address", "address /metrics is served on, it should only be reachable from the internal network", func(cfg *Config, value string) error {
+		cfg.Server.MetricsAddress = value
+		return nil
+	}},
+	{"server.read_header_timeout", "users_server_read_header_timeout", "timeout to read the request headers", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Server.ReadHeaderTimeout)
+	}},
+	{"server.read_timeout", "users_server_read_timeout", "timeout to read the whole request", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Server.ReadTimeout)
+	}},
+	{"server.write_timeout", "users_server_write_timeout", "timeout to write the response", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Server.WriteTimeout)
+	}},
+	{"server.idle_timeout", "users_server_idle_timeout", "how long a keep-alive connection can stay idle", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Server.IdleTimeout)
+	}},
+	{"server.shutdown_timeout", "users_server_shutdown_timeout", "how long in-flight requests have to finish on shutdown", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Server.ShutdownTimeout)
+	}},
+	{"server.drain_delay", "users_server_drain_delay", "how long /readyz reports draining before the server stops accepting requests", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Server.DrainDelay)
+	}},
+	{"server.health_timeout", "users_server_health_timeout", "how long each readiness check has to answer", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Server.HealthTimeout)
+	}},
+	{"database.username", "mysql_users_username", "mysql username", func(cfg *Config, value string) error {
+		cfg.Database.Username = value
+		return nil
+	}},
+	{"database.password", "mysql_users_password", "mysql password", func(cfg *Config, value string) error {
+		cfg.Database.Password = value
+		return nil
+	}},
+	{"database.password_file", "mysql_users_password_file", "file containing the mysql password", func(cfg *Config, value string) error {
+		cfg.Database.PasswordFile = value
+		return nil
+	}},
+	{"database.host", "mysql_users_host", "mysql host and port", func(cfg *Config, value string) error {
+		cfg.Database.Host = value
+		return nil
+	}},
+	{"database.schema", "mysql_users_schema", "mysql schema", func(cfg *Config, value string) error {
+		cfg.Database.Schema = value
+		return nil
+	}},
+	{"database.connect_timeout", "mysql_users_connect_timeout", "timeout to open a connection, e.g. 5s", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Database.ConnectTimeout)
+	}},
+	{"database.read_timeout", "mysql_users_read_timeout", "timeout to read from a connection", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Database.ReadTimeout)
+	}},
+	{"database.write_timeout", "mysql_users_write_timeout", "timeout to write to a connection", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Database.WriteTimeout)
+	}},
+	{"database.connect_attempts", "mysql_users_connect_attempts", "how many times to try to connect at startup, 0 means until stopped", func(cfg *Config, value string) error {
+		return parseInt(value, &cfg.Database.ConnectAttempts)
+	}},
+	{"database.retry_backoff", "mysql_users_retry_backoff", "first wait between two connection attempts, doubled each time", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Database.RetryBackoff)
+	}},
+	{"database.retry_max_backoff", "mysql_users_retry_max_backoff", "longest wait between two connection attempts", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Database.RetryMaxBackoff)
+	}},
+	{"database.max_open_conns", "mysql_users_max_open_conns", "maximum number of open connections, 0 means no limit", func(cfg *Config, value string) error {
+		return parseInt(value, &cfg.Database.MaxOpenConns)
+	}},
+	{"database.max_idle_conns", "mysql_users_max_idle_conns", "maximum number of idle connections kept in the pool", func(cfg *Config, value string) error {
+		return parseInt(value, &cfg.Database.MaxIdleConns)
+	}},
+	{"database.conn_max_lifetime", "mysql_users_conn_max_lifetime", "how long a connection can be reused, 0 means forever", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Database.ConnMaxLifetime)
+	}},
+	{"database.conn_max_idle_time", "mysql_users_conn_max_idle_time", "how long a connection can stay idle, 0 means forever", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Database.ConnMaxIdleTime)
+	}},
+	{"log.level", "users_log_level", "log level: debug, info, warn or error", func(cfg *Config, value string) error {
+		cfg.Log.Level = strings.ToLower(value)
+		return nil
+	}},
+	{"log.encoding", "users_log_encoding", "log encoding: json or console", func(cfg *Config, value string) error {
+		cfg.Log.Encoding = strings.ToLower(value)
+		return nil
+	}},
+	{"log.outputs", "users_log_outputs", "comma separated log outputs: stdout, stderr or file paths, e.g. stdout,/var/log/users.log", func(cfg *Config, value string) error {
+		cfg.Log.Outputs = splitList(value)
+		return nil
+	}},
+	{"log.file.max_size_mb", "users_log_file_max_size_mb", "size in megabytes at which a log file is rotated", func(cfg *Config, value string) error {
+		return parseInt(value, &cfg.Log.File.MaxSizeMB)
+	}},
+	{"log.file.max_backups", "users_log_file_max_backups", "how many rotated log files are kept, 0 keeps them all", func(cfg *Config, value string) error {
+		return parseInt(value, &cfg.Log.File.MaxBackups)
+	}},
+	{"log.file.max_age_days", "users_log_file_max_age_days", "how many days rotated log files are kept, 0 keeps them all", func(cfg *Config, value string) error {
+		return parseInt(value, &cfg.Log.File.MaxAgeDays)
+	}},
+	{"log.file.compress", "users_log_file_compress", "gzip the rotated log files", func(cfg *Config, value string) error {
+		compress, err := strconv.ParseBool(value)
+		cfg.Log.File.Compress = compress
+		return err
+	}},
+	{"log.access.sample_ratio", "users_log_access_sample_ratio", "ratio of successful requests written to the access log, from 0 to 1", func(cfg *Config, value string) error {
+		return parseFloat(value, &cfg.Log.Access.SampleRatio)
+	}},
+	{"log.access.exclude_paths", "users_log_access_exclude_paths", "comma separated paths never written to the access log", func(cfg *Config, value string) error {
+		cfg.Log.Access.ExcludePaths = splitList(value)
+		return nil
+	}},
+	{"users.require_if_match", "users_require_if_match", "reject PUT, PATCH and DELETE without If-Match", func(cfg *Config, value string) error {
+		requireIfMatch, err := strconv.ParseBool(value)
+		cfg.Users.RequireIfMatch = requireIfMatch
+		return err
+	}},
+	{"users.idempotency_ttl", "users_idempotency_ttl", "how long idempotency keys are kept, e.g. 24h", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Users.IdempotencyTTL)
+	}},
+	{"users.idempotency_lease", "users_idempotency_lease", "how long a request keeps its idempotency key before it can be claimed again, e.g. 1m", func(cfg *Config, value string) error {
+		return parseDuration(value, &cfg.Users.IdempotencyLease)
+	}},
+	{"tracing.exporter", "users_tracing_exporter", "where spans are sent: none, otlp or stdout", func(cfg *Config, value string) error {
+		cfg.Tracing.Exporter = strings.ToLower(value)
+		return nil
+	}},
+	{"tracing.endpoint", "users_tracing_endpoint", "host:port of the OTLP http collector, e.g. localhost:4318", func(cfg *Config, value string) error {
+		cfg.Tracing.Endpoint = value
+		return nil
+	}},
+	{"tracing.insecure", "users_tracing_insecure", "send spans to the OTLP collector without TLS", func(cfg *Config, value string) error {
+		insecure, err := strconv.ParseBool(value)
+		cfg.Tracing.Insecure = insecure
+		return err
+	}},
+	{"tracing.file", "users_tracing_file", "file the stdout exporter writes to instead of standard output", func(cfg *Config, value string) error {
+		cfg.Tracing.File = value
+		return nil
+	}},
+	{"tracing.service_name", "users_tracing_service_name", "service name of the spans", func(cfg *Config, value string) error {
+		cfg.Tracing.ServiceName = value
+		return nil
+	}},
+	{"tracing.sample_ratio", "users_tracing_sample_ratio", "ratio of new traces which are recorded, from 0 to 1", func(cfg *Config, value string) error {
+		return parseFloat(value, &cfg.Tracing.SampleRatio)
+	}},
+	{"admin.token", "users_admin_token", "bearer token of the /admin endpoints, they are disabled without it", func(cfg *Config, value string) error {
+		cfg.Admin.Token = value
+		return nil
+	}},
+	{"admin.token_file", "users_admin_token_file", "file containing the bearer token of the /admin endpoints", func(cfg *Config, value string) error {
+		cfg.Admin.TokenFile = value
+		return nil
+	}},
+}
+
+// configFileEnv is the environment variable of the config file, the -config flag wins over it
+const configFileEnv = "users_config_file"
+
+// Load is used to build the configuration at startup, each source overrides the previous one:
+// defaults, then the config file (yaml or toml), then environment variables, then command line flags.
+// Secrets given as files are read last, and the result is validated
+func Load(args []string) (*Config, error) {
+	flags := flag.NewFlagSet("users_api", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv(configFileEnv), "path of the yaml or toml config file")
+	for _, s := range settings {
+		flags.String(s.flag, "", fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if *configFile != "" {
+		if err := loadFile(*configFile, &cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok && value != "" {
+			if err := s.apply(&cfg, value); err != nil {
+				return nil, fmt.Errorf("invalid value for %s: %w", s.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	// Visit only goes through the flags that were set on the command line
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name && flagErr == nil {
+				if err := s.apply(&cfg, f.Value.String()); err != nil {
+					flagErr = fmt.Errorf("invalid value for -%s: %w", s.flag, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := cfg.readSecrets(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// loadFile is used to read the config file, the format comes from the extension
+func loadFile(path string, cfg *Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error when trying to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, cfg)
+	case ".toml":
+		err = toml.Unmarshal(content, cfg)
+	default:
+		return fmt.Errorf("config file %s should be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("error when trying to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// readSecrets replaces the secrets given as files with the content of the files
+func (cfg *Config) readSecrets() error {
+	if cfg.Database.PasswordFile != "" {
+		password, err := os.ReadFile(cfg.Database.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("error when trying to read database password file: %w", err)
+		}
+		cfg.Database.Password = strings.TrimSpace(string(password))
+	}
+	if cfg.Admin.TokenFile != "" {
+		token, err := os.ReadFile(cfg.Admin.TokenFile)
+		if err != nil {
+			return fmt.Errorf("error when trying to read admin token file: %w", err)
+		}
+		cfg.Admin.Token = strings.TrimSpace(string(token))
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	result := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func parseDuration(value string, target *time.Duration) error {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*target = duration
+	return nil
+}
+
+func parseInt(value string, target *int) error {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	*target = number
+	return nil
+}
+
+func parseFloat(value string, target *float64) error {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	*target = number
+	return nil
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile creates a file in a temporary directory and returns its path
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// clearEnv unsets the variables the tests use, so the environment running the tests cannot change them
+func clearEnv(t *testing.T) {
+	for _, name := range []string{configFileEnv, "mysql_users_username", "mysql_users_schema", "mysql_users_host", "users_server_address", "users_log_level", "mysql_users_password_file"} {
+		t.Setenv(name, "")
+	}
+}
+
+const yamlConfig = `
+server:
+  address: ":8081"
+  read_timeout: 20s
+database:
+  username: file_user
+  host: file_host:3306
+  schema: file_schema
+log:
+  level: warn
+`
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		args        []string
+		wantAddress string
+		wantHost    string
+		wantLevel   string
+	}{
+		{
+			name:        "file over defaults",
+			wantAddress: ":8081",
+			wantHost:    "file_host:3306",
+			wantLevel:   "warn",
+		},
+		{
+			name:        "env over file",
+			env:         map[string]string{"mysql_users_host": "env_host:3306", "users_log_level": "error"},
+			wantAddress: ":8081",
+			wantHost:    "env_host:3306",
+			wantLevel:   "error",
+		},
+		{
+			name:        "flags over env",
+			env:         map[string]string{"mysql_users_host": "env_host:3306", "users_log_level": "error"},
+			args:        []string{"-database.host", "flag_host:3306", "-server.address", ":8082"},
+			wantAddress: ":8082",
+			wantHost:    "flag_host:3306",
+			wantLevel:   "error",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			args := append([]string{"-config", writeFile(t, "config.yaml", yamlConfig)}, test.args...)
+
+			cfg, err := Load(args)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.Server.Address != test.wantAddress || cfg.Database.Host != test.wantHost || cfg.Log.Level != test.wantLevel {
+				t.Errorf("address, host, level = %s, %s, %s, want %s, %s, %s",
+					cfg.Server.Address, cfg.Database.Host, cfg.Log.Level, test.wantAddress, test.wantHost, test.wantLevel)
+			}
+			// nothing overrides these, so they keep the value of the file or the default
+			if cfg.Server.ReadTimeout != 20*time.Second {
+				t.Errorf("read timeout = %s, want 20s from the file", cfg.Server.ReadTimeout)
+			}
+			if cfg.Server.WriteTimeout != Default().Server.WriteTimeout {
+				t.Errorf("write timeout = %s, want the default %s", cfg.Server.WriteTimeout, Default().Server.WriteTimeout)
+			}
+		})
+	}
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		wantErr  string
+		wantUser string
+	}{
+		{"yaml", "config.yml", "database:\n  username: yaml_user\n  schema: users_db\n", "", "yaml_user"},
+		{"toml", "config.toml", "[database]\nusername = \"toml_user\"\nschema = \"users_db\"\n", "", "toml_user"},
+		{"unknown extension", "config.json", "{}", "should be .yaml, .yml or .toml", ""},
+		{"broken yaml", "config.yaml", "database: [", "error when trying to parse config file", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv(configFileEnv, writeFile(t, test.file, test.content))
+			t.Setenv("mysql_users_host", "localhost:3306")
+
+			cfg, err := Load(nil)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.Database.Username != test.wantUser {
+				t.Errorf("username = %s, want %s", cfg.Database.Username, test.wantUser)
+			}
+		})
+	}
+}
+
+func TestLoadSecretFile(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("mysql_users_password_file", writeFile(t, "password", "s3cret\n"))
+
+	cfg, err := Load([]string{"-database.username", "root", "-database.host", "localhost:3306", "-database.schema", "users_db", "-database.password", "ignored"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Database.Password != "s3cret" {
+		t.Errorf("password = %q, want the trimmed content of the file", cfg.Database.Password)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"bad duration", []string{"-server.read_timeout", "soon"}, "invalid value for -server.read_timeout"},
+		{"missing required", []string{"-database.host", "localhost:3306"}, "database.username is required"},
+		{"unknown flag", []string{"-nope", "1"}, "flag provided but not defined"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnv(t)
+			if _, err := Load(test.args); err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
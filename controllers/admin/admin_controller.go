@@ -0,0 +1,41 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/annazhao/bookstore_users_api/logger"
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"github.com/annazhao/bookstore_users_api/utils/responses"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// LogLevel is the body of the log level endpoints, e.g. {"level": "debug"}
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+// GetLogLevel is used to see the current level of the logger, in url: GET /admin/log/level
+func GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, LogLevel{Level: logger.GetLevel()})
+}
+
+// SetLogLevel is used to change the level of the logger without restarting, in url: PUT /admin/log/level
+func SetLogLevel(c *gin.Context) {
+	var request LogLevel
+	if err := c.ShouldBindJSON(&request); err != nil {
+		restErr := errors.NewBadRequestError(errors.CodeInvalidJSON, "invalid json body").Wrap("error when trying to bind log level", err)
+		responses.Error(c, restErr)
+		return
+	}
+
+	previous := logger.GetLevel()
+	if err := logger.SetLevel(request.Level); err != nil {
+		restErr := errors.NewBadRequestError(errors.CodeInvalidParameter, "level should be one of debug, info, warn, error").WithMessageKey("INVALID_PARAMETER.level")
+		responses.Error(c, restErr)
+		return
+	}
+	// logged at error, the highest level we allow, so the change is written whatever the new level is
+	logger.ErrorContext(c.Request.Context(), "log level changed", nil, zap.String("from", previous), zap.String("to", logger.GetLevel()))
+	c.JSON(http.StatusOK, LogLevel{Level: logger.GetLevel()})
+}
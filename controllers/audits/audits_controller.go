@@ -0,0 +1,41 @@
+package audits
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/annazhao/bookstore_users_api/services"
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"github.com/annazhao/bookstore_users_api/utils/responses"
+	"github.com/gin-gonic/gin"
+)
+
+// Search is used to get the audit trail, in url: /admin/audits?user_id=1&actor=2&limit=20
+func Search(c *gin.Context) {
+	var userID int64
+	if userIDParam := c.Query("user_id"); userIDParam != "" {
+		var err error
+		if userID, err = strconv.ParseInt(userIDParam, 10, 64); err != nil {
+			restErr := errors.NewBadRequestError(errors.CodeInvalidParameter, "user id should be a number").WithMessageKey("INVALID_PARAMETER.user_id")
+			responses.Error(c, restErr)
+			return
+		}
+	}
+
+	var limit int
+	if limitParam := c.Query("limit"); limitParam != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitParam); err != nil {
+			restErr := errors.NewBadRequestError(errors.CodeInvalidParameter, "limit should be a number").WithMessageKey("INVALID_PARAMETER.limit")
+			responses.Error(c, restErr)
+			return
+		}
+	}
+
+	entries, err := services.AuditsService.SearchAudits(c.Request.Context(), userID, c.Query("actor"), limit)
+	if err != nil {
+		responses.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,13 @@
+package database
+
+import (
+	"net/http"
+
+	usersdb "github.com/annazhao/bookstore_users_api/datasources/mysql/users_db"
+	"github.com/gin-gonic/gin"
+)
+
+// Stats is used to see how the database connection pool is used, in url: /admin/database/stats
+func Stats(c *gin.Context) {
+	c.JSON(http.StatusOK, usersdb.GetStats())
+}
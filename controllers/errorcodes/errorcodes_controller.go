@@ -0,0 +1,13 @@
+package errorcodes
+
+import (
+	"net/http"
+
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// List is used to document every error code the api can return, so clients can branch on codes instead of messages
+func List(c *gin.Context) {
+	c.JSON(http.StatusOK, errors.GetCodes())
+}
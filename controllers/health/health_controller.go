@@ -0,0 +1,45 @@
+package health
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/annazhao/bookstore_users_api/health"
+	"github.com/gin-gonic/gin"
+)
+
+// checkTimeout is how long each readiness check has to answer
+var checkTimeout = 2 * time.Second
+
+// Configure is used at startup to set how long each readiness check has to answer
+func Configure(timeout time.Duration) {
+	checkTimeout = timeout
+}
+
+// Healthz is the liveness probe, it only tells the process is alive and serving requests
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, health.Report{Status: health.StatusUp, Checks: []health.Result{}})
+}
+
+// Readyz is the readiness probe, it runs every registered check and answers 503 if one of them is down,
+// it is public so only the status of each check is given, the errors can tell how the database is reached
+func Readyz(c *gin.Context) {
+	report := health.Run(c.Request.Context(), checkTimeout)
+	for i := range report.Checks {
+		report.Checks[i].Error = ""
+	}
+	c.JSON(getReadyzStatus(report), report)
+}
+
+// ReadyzDetails is the readiness probe with the error of every check that is down, in url: /admin/readyz
+func ReadyzDetails(c *gin.Context) {
+	report := health.Run(c.Request.Context(), checkTimeout)
+	c.JSON(getReadyzStatus(report), report)
+}
+
+func getReadyzStatus(report health.Report) int {
+	if report.Status != health.StatusUp {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
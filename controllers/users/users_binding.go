@@ -0,0 +1,102 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/annazhao/bookstore_users_api/domain/users"
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// bindJSON is used instead of c.ShouldBindJSON so unknown fields and wrong types are reported field by field,
+// the body is first read as a json object so every bad field is in the causes, not only the first one
+func bindJSON(c *gin.Context, target interface{}) *errors.RestErr {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return errors.NewBadRequestError(errors.CodeInvalidJSON, "invalid request body").WithMessageKey("INVALID_JSON.request_body").
+			Wrap("error when trying to read request body", err)
+	}
+
+	var document map[string]json.RawMessage
+	// a body which is not a json object (e.g. null or an array) cannot be any of our requests
+	if err := json.Unmarshal(body, &document); err != nil || document == nil {
+		return errors.NewBadRequestError(errors.CodeInvalidJSON, "invalid json body").Wrap("error when trying to decode json body", err)
+	}
+
+	fields := getJSONFields(reflect.TypeOf(target).Elem())
+	names := make([]string, 0, len(document))
+	for name := range document {
+		names = append(names, name)
+	}
+	// the causes are sorted, so the same body always gives the same error
+	sort.Strings(names)
+
+	causes := make([]errors.Cause, 0)
+	for _, name := range names {
+		fieldType, ok := getJSONField(fields, name)
+		if !ok {
+			causes = append(causes, errors.Cause{
+				Field:   name,
+				Code:    users.CauseUnknownField,
+				Message: fmt.Sprintf("unknown field %s", name),
+				Params:  map[string]string{"field": name},
+			})
+			continue
+		}
+		if err := json.Unmarshal(document[name], reflect.New(fieldType).Interface()); err != nil {
+			causes = append(causes, errors.Cause{
+				Field:   name,
+				Code:    users.CauseInvalidType,
+				Message: fmt.Sprintf("%s should be a %s", name, fieldType.Kind()),
+				Params:  map[string]string{"field": name, "type": fieldType.Kind().String()},
+			})
+		}
+	}
+	if len(causes) > 0 {
+		return errors.NewValidationError(causes)
+	}
+
+	// every field is known and has the right type, so this cannot fail on a field anymore
+	if err := json.Unmarshal(body, target); err != nil {
+		return errors.NewBadRequestError(errors.CodeInvalidJSON, "invalid json body").Wrap("error when trying to decode json body", err)
+	}
+	return nil
+}
+
+// getJSONFields returns the type of every field of the struct keyed by its json name
+func getJSONFields(structType reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// getJSONField finds the field like encoding/json does, an exact name first and then ignoring the case
+func getJSONField(fields map[string]reflect.Type, name string) (reflect.Type, bool) {
+	if fieldType, ok := fields[name]; ok {
+		return fieldType, true
+	}
+	for fieldName, fieldType := range fields {
+		if strings.EqualFold(fieldName, name) {
+			return fieldType, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,83 @@
+package users
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/annazhao/bookstore_users_api/domain/users"
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"github.com/gin-gonic/gin"
+)
+
+func TestBindJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		want       users.User
+		wantCode   string
+		wantCauses []string
+	}{
+		{
+			name: "valid",
+			body: `{"first_name": "Ann", "email": "ann@example.com", "password": "secret123"}`,
+			want: users.User{FirstName: "Ann", Email: "ann@example.com", Password: "secret123"},
+		},
+		{
+			name: "field name in another case",
+			body: `{"First_Name": "Ann"}`,
+			want: users.User{FirstName: "Ann"},
+		},
+		{
+			name:       "every bad field is reported",
+			body:       `{"nickname": "annie", "first_name": 1, "version": "two", "email": "ann@example.com", "admin": true}`,
+			wantCode:   errors.CodeValidationFailed,
+			wantCauses: []string{"admin:unknown_field", "first_name:invalid_type", "nickname:unknown_field", "version:invalid_type"},
+		},
+		{
+			name:     "not json",
+			body:     `{"first_name": `,
+			wantCode: errors.CodeInvalidJSON,
+		},
+		{
+			name:     "not an object",
+			body:     `["first_name"]`,
+			wantCode: errors.CodeInvalidJSON,
+		},
+		{
+			name:     "null",
+			body:     `null`,
+			wantCode: errors.CodeInvalidJSON,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(test.body))
+
+			var user users.User
+			err := bindJSON(c, &user)
+			if test.wantCode == "" {
+				if err != nil {
+					t.Fatalf("bindJSON() = %v, want nil", err)
+				}
+				if user != test.want {
+					t.Errorf("user = %+v, want %+v", user, test.want)
+				}
+				return
+			}
+			if err == nil || err.Code != test.wantCode {
+				t.Fatalf("bindJSON() = %v, want %s", err, test.wantCode)
+			}
+			causes := make([]string, 0)
+			for _, cause := range err.Causes {
+				causes = append(causes, cause.Field+":"+cause.Code)
+			}
+			if test.wantCauses != nil && !reflect.DeepEqual(causes, test.wantCauses) {
+				t.Errorf("causes = %v, want %v", causes, test.wantCauses)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package users
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/annazhao/bookstore_users_api/domain/users"
+	"github.com/annazhao/bookstore_users_api/utils/dates"
+	"github.com/gin-gonic/gin"
+)
+
+// setCacheHeaders is used to send the validators of the user, so the client can make conditional requests next time
+func setCacheHeaders(c *gin.Context, user *users.User) {
+	setETag(c, user.Version)
+	if lastModified, err := dates.ParseDBDate(user.DateUpdated); err == nil {
+		c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+	// the body is different for public and private requests
+	c.Header("Vary", "X-Public")
+}
+
+// isNotModified returns true if the client already has the current version of the user,
+// If-None-Match wins over If-Modified-Since when both are sent (RFC 7232 section 6)
+func isNotModified(c *gin.Context, user *users.User) bool {
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		return etagListContains(ifNoneMatch, etag(user.Version))
+	}
+
+	ifModifiedSince := c.GetHeader("If-Modified-Since")
+	if ifModifiedSince == "" {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	lastModified, err := dates.ParseDBDate(user.DateUpdated)
+	if err != nil {
+		return false
+	}
+	// Last-Modified only has a precision of one second
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// etagListContains uses the weak comparison, which is the one If-None-Match asks for
+func etagListContains(list string, tag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package users
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/annazhao/bookstore_users_api/domain/users"
+	"github.com/gin-gonic/gin"
+)
+
+func TestIsNotModified(t *testing.T) {
+	// Last-Modified is sent as Thu, 02 Jan 2020 15:04:05 GMT for this user
+	user := &users.User{Version: 3, DateUpdated: "2020-01-02 15:04:05"}
+
+	tests := []struct {
+		name            string
+		dateUpdated     string
+		ifNoneMatch     string
+		ifModifiedSince string
+		want            bool
+	}{
+		{"no condition", "", "", "", false},
+		{"current tag", "", `"3"`, "", true},
+		{"old tag", "", `"2"`, "", false},
+		{"weak current tag", "", `W/"3"`, "", true},
+		{"current tag in a list", "", `"1", "3"`, "", true},
+		{"any tag", "", "*", "", true},
+		{"not modified since", "", "", "Thu, 02 Jan 2020 15:04:05 GMT", true},
+		{"modified since", "", "", "Thu, 02 Jan 2020 15:04:04 GMT", false},
+		{"bad date", "", "", "yesterday", false},
+		{"If-None-Match wins when it matches", "", `"3"`, "Thu, 02 Jan 2020 15:04:04 GMT", true},
+		{"If-None-Match wins when it does not match", "", `"2"`, "Thu, 02 Jan 2020 15:04:05 GMT", false},
+		{"fraction of a second is ignored", "2020-01-02 15:04:05.750", "", "Thu, 02 Jan 2020 15:04:05 GMT", true},
+		{"a second later is modified", "2020-01-02 15:04:06", "", "Thu, 02 Jan 2020 15:04:05 GMT", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user := *user
+			if test.dateUpdated != "" {
+				user.DateUpdated = test.dateUpdated
+			}
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/users/1", nil)
+			if test.ifNoneMatch != "" {
+				c.Request.Header.Set("If-None-Match", test.ifNoneMatch)
+			}
+			if test.ifModifiedSince != "" {
+				c.Request.Header.Set("If-Modified-Since", test.ifModifiedSince)
+			}
+
+			if got := isNotModified(c, &user); got != test.want {
+				t.Errorf("isNotModified() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestETagListContains(t *testing.T) {
+	tests := []struct {
+		list string
+		want bool
+	}{
+		{`"3"`, true},
+		{`"4"`, false},
+		{`W/"3"`, true},
+		{`"1","3"`, true},
+		{` "1" , W/"3" `, true},
+		{`"1", "2"`, false},
+		{"*", true},
+		{`"33"`, false},
+		{"3", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if got := etagListContains(test.list, `"3"`); got != test.want {
+			t.Errorf("etagListContains(%s) = %v, want %v", test.list, got, test.want)
+		}
+	}
+}
@@ -1,40 +1,68 @@
 package users
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"strconv"
 
+	"github.com/annazhao/bookstore_users_api/domain/audits"
 	"github.com/annazhao/bookstore_users_api/domain/users"
 	"github.com/annazhao/bookstore_users_api/services"
+	"github.com/annazhao/bookstore_users_api/utils/dates"
 	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"github.com/annazhao/bookstore_users_api/utils/responses"
 	"github.com/gin-gonic/gin"
 )
 
 func getUserID(userIDParam string) (int64, *errors.RestErr) {
 	userID, userErr := strconv.ParseInt(userIDParam, 10, 64)
 	if userErr != nil {
-		return 0, errors.NewBadRequestError("user id should be a number")
+		return 0, errors.NewBadRequestError(errors.CodeInvalidUserID, "user id should be a number")
 	}
 	return userID, nil
 }
 
+// requestContext returns the context of the request with its origin attached, the request id is set by the RequestID middleware.
+// The actor is the identity verified by an authentication middleware when there is one, otherwise it is only
+// what the caller claims in X-Caller-Id, so it is recorded as unverified in the audit trail
+func requestContext(c *gin.Context) context.Context {
+	actor, verified := c.GetString(audits.AuthenticatedActorKey), true
+	if actor == "" {
+		actor, verified = c.GetHeader("X-Caller-Id"), false
+	}
+	if actor == "" {
+		actor = "anonymous"
+	}
+	// the header is not checked anywhere else, so a long one must not make every audited write fail
+	if runes := []rune(actor); len(runes) > audits.MaxActorLength {
+		actor = string(runes[:audits.MaxActorLength])
+	}
+	return audits.NewContext(c.Request.Context(), audits.Origin{
+		Actor:         actor,
+		ActorVerified: verified,
+		RequestID:     c.GetHeader("X-Request-Id"),
+		IP:            c.ClientIP(),
+	})
+}
+
 // Create function here is used to parse the JSON data from request body to a new User instance, and save it into the database
 func Create(c *gin.Context) {
 	var user users.User
 	// get the user from JSON request body
-	if err := c.ShouldBindJSON(&user); err != nil {
-		restErr := errors.NewBadRequestError("invalid json body")
-		c.JSON(restErr.Status, restErr)
+	if restErr := bindJSON(c, &user); restErr != nil {
+		responses.Error(c, restErr)
 		return
 	}
 
 	// create this user, and save it into the database
-	result, saveErr := services.UsersService.CreateUser(user)
+	result, saveErr := services.UsersService.CreateUser(requestContext(c), user)
 	if saveErr != nil {
-		c.JSON(saveErr.Status, saveErr)
+		responses.Error(c, saveErr)
 		return
 	}
 	// return back a JSON result
+	setETag(c, result.Version)
 	c.JSON(http.StatusCreated, result.Marshal(c.GetHeader("X-Public") == "true"))
 
 }
@@ -44,56 +72,142 @@ func Get(c *gin.Context) {
 	// c.Param("user_id") is to get the parameter value in url /users/:user_id
 	userID, idErr := getUserID(c.Param("user_id"))
 	if idErr != nil {
-		c.JSON(idErr.Status, idErr)
+		responses.Error(c, idErr)
+		return
 	}
 
-	user, getErr := services.UsersService.GetUser(userID)
+	var user *users.User
+	var getErr *errors.RestErr
+	// in url: /users/1?as_of=2020-01-02T15:04:05Z, we return the user as it looked at that time
+	if asOfParam := c.Query("as_of"); asOfParam != "" {
+		asOf, err := dates.ParseAPIDate(asOfParam)
+		if err != nil {
+			restErr := errors.NewBadRequestError(errors.CodeInvalidParameter, "as_of should be a RFC 3339 timestamp").WithMessageKey("INVALID_PARAMETER.as_of")
+			responses.Error(c, restErr)
+			return
+		}
+		user, getErr = services.UsersService.GetUserAsOf(requestContext(c), userID, asOf)
+	} else {
+		user, getErr = services.UsersService.GetUser(requestContext(c), userID)
+	}
 	if getErr != nil {
-		c.JSON(getErr.Status, getErr)
+		responses.Error(c, getErr)
 		return
 	}
 
+	setCacheHeaders(c, user)
+	if isNotModified(c, user) {
+		c.Status(http.StatusNotModified)
+		return
+	}
 	// to see whether it's a public request or private, we can see from "X-Public" in request header
 	c.JSON(http.StatusOK, user.Marshal(c.GetHeader("X-Public") == "true"))
 }
 
+// Versions is to get every revision of the user, oldest first
+func Versions(c *gin.Context) {
+	userID, idErr := getUserID(c.Param("user_id"))
+	if idErr != nil {
+		responses.Error(c, idErr)
+		return
+	}
+
+	versions, err := services.UsersService.GetUserVersions(requestContext(c), userID)
+	if err != nil {
+		responses.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, versions.Marshal(c.GetHeader("X-Public") == "true"))
+}
+
 // Update is to update user in database
 func Update(c *gin.Context) {
 	// first get user_id from url
 	userID, idErr := getUserID(c.Param("user_id"))
 	if idErr != nil {
-		c.JSON(idErr.Status, idErr)
+		responses.Error(c, idErr)
+		return
+	}
+
+	// the version the client has seen comes from If-Match, never from the body
+	expectedVersion, versionErr := getIfMatchVersion(c, userID)
+	if versionErr != nil {
+		responses.Error(c, versionErr)
+		return
+	}
+
+	// a PATCH with a merge patch or json patch body is applied on the whole user document,
+	// only application/json still uses the partial update below, any other content type gets 415
+	if c.Request.Method == http.MethodPatch && c.ContentType() != gin.MIMEJSON {
+		patch(c, userID, expectedVersion)
+		return
 	}
 
 	var user users.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		restErr := errors.NewBadRequestError("invalid json body")
-		c.JSON(restErr.Status, restErr)
+	if restErr := bindJSON(c, &user); restErr != nil {
+		responses.Error(c, restErr)
 		return
 	}
 	user.ID = userID
+	user.Version = expectedVersion
 
-	// if it's PUT method, isPartial will be false; if it's PATCH, isPartial will be true
+	// if it's PUT method, isPartial will be false; if it's PATCH, isPartial will be true.
+	// a PATCH with application/json cannot tell an absent field from "", so "" leaves the field unchanged,
+	// clients that need to clear a field use application/merge-patch+json with null instead
 	isPartial := c.Request.Method == http.MethodPatch
 
-	result, err := services.UsersService.UpdateUser(isPartial, user)
+	result, err := services.UsersService.UpdateUser(requestContext(c), isPartial, user)
+	if err != nil {
+		responses.Error(c, err)
+		return
+	}
+	setETag(c, result.Version)
+	c.JSON(http.StatusOK, result.Marshal(c.GetHeader("X-Public") == "true"))
+}
+
+// patch is used to read the patch document from the request body and apply it on the user,
+// the content type tells which patch format the body is in
+func patch(c *gin.Context, userID int64, expectedVersion int64) {
+	body, err := c.GetRawData()
 	if err != nil {
-		c.JSON(err.Status, err)
+		restErr := errors.NewBadRequestError(errors.CodeInvalidJSON, "invalid request body").WithMessageKey("INVALID_JSON.request_body")
+		responses.Error(c, restErr)
 		return
 	}
+
+	userPatch, ok := users.NewPatch(c.ContentType(), body)
+	if !ok {
+		restErr := errors.NewUnsupportedMediaTypeError(errors.CodeUnsupportedMediaType, fmt.Sprintf("content type %s is not supported", c.ContentType())).
+			WithParam("content_type", c.ContentType())
+		responses.Error(c, restErr)
+		return
+	}
+	result, patchErr := services.UsersService.PatchUser(requestContext(c), userID, expectedVersion, userPatch)
+	if patchErr != nil {
+		responses.Error(c, patchErr)
+		return
+	}
+	setETag(c, result.Version)
 	c.JSON(http.StatusOK, result.Marshal(c.GetHeader("X-Public") == "true"))
 }
 
-// Delete is to delete user in database
+// Delete is to delete user in database, the user is moved to the deleted status and kept with its history
 func Delete(c *gin.Context) {
 	// first get user_id from url
 	userID, idErr := getUserID(c.Param("user_id"))
 	if idErr != nil {
-		c.JSON(idErr.Status, idErr)
+		responses.Error(c, idErr)
+		return
+	}
+
+	expectedVersion, versionErr := getIfMatchVersion(c, userID)
+	if versionErr != nil {
+		responses.Error(c, versionErr)
+		return
 	}
 
-	if err := services.UsersService.DeleteUser(userID); err != nil {
-		c.JSON(err.Status, err)
+	if err := services.UsersService.DeleteUser(requestContext(c), userID, expectedVersion); err != nil {
+		responses.Error(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
@@ -103,9 +217,9 @@ func Delete(c *gin.Context) {
 func Search(c *gin.Context) {
 	// in url: /internal/users/search?status=active, if we want to get "active" status, we need to use c.Query()
 	status := c.Query("status")
-	users, err := services.UsersService.SearchUser(status)
+	users, err := services.UsersService.SearchUser(requestContext(c), status)
 	if err != nil {
-		c.JSON(err.Status, err)
+		responses.Error(c, err)
 		return
 	}
 
@@ -113,18 +227,68 @@ func Search(c *gin.Context) {
 	c.JSON(http.StatusOK, users.Marshal(c.GetHeader("X-Public") == "true"))
 }
 
+// changeStatus is shared by the status endpoints below, it moves the user in url from the "from" status to the "to" status
+func changeStatus(c *gin.Context, from string, to string) {
+	userID, idErr := getUserID(c.Param("user_id"))
+	if idErr != nil {
+		responses.Error(c, idErr)
+		return
+	}
+
+	var request users.StatusChangeRequest
+	// the body is optional for some statuses, so an empty body is fine here
+	if c.Request.ContentLength != 0 {
+		if restErr := bindJSON(c, &request); restErr != nil {
+			responses.Error(c, restErr)
+			return
+		}
+	}
+
+	user, err := services.UsersService.ChangeUserStatus(requestContext(c), userID, from, to, request)
+	if err != nil {
+		responses.Error(c, err)
+		return
+	}
+	setETag(c, user.Version)
+	c.JSON(http.StatusOK, user.Marshal(c.GetHeader("X-Public") == "true"))
+}
+
+// Activate is to activate a new user, it can only log in after that
+func Activate(c *gin.Context) {
+	changeStatus(c, users.StatusPending, users.StatusActive)
+}
+
+// Suspend is to suspend an active user, a reason is required
+func Suspend(c *gin.Context) {
+	changeStatus(c, users.StatusActive, users.StatusSuspended)
+}
+
+// Reactivate is to move a suspended user back to active
+func Reactivate(c *gin.Context) {
+	changeStatus(c, users.StatusSuspended, users.StatusActive)
+}
+
+// Lock is to lock an active user, e.g. after suspicious activity, a reason is required
+func Lock(c *gin.Context) {
+	changeStatus(c, users.StatusActive, users.StatusLocked)
+}
+
+// Unlock is to move a locked user back to active
+func Unlock(c *gin.Context) {
+	changeStatus(c, users.StatusLocked, users.StatusActive)
+}
+
 // Login is use to find user by email and password in database, then create access token
 func Login(c *gin.Context) {
 	var request users.LoginRequest
-	if err := c.ShouldBindJSON(&request); err != nil {
-		restErr := errors.NewBadRequestError("invalid json body")
-		c.JSON(restErr.Status, restErr)
+	if restErr := bindJSON(c, &request); restErr != nil {
+		responses.Error(c, restErr)
 		return
 	}
 
-	user, err := services.UsersService.LoginUser(request)
+	user, err := services.UsersService.LoginUser(requestContext(c), request)
 	if err != nil {
-		c.JSON(err.Status, err)
+		responses.Error(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, user.Marshal(c.GetHeader("X-Public") == "true"))
@@ -0,0 +1,70 @@
+package users
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/annazhao/bookstore_users_api/domain/audits"
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// the requests below are all rejected before the service is called, so no database is needed
+func TestUpdatePatchContentType(t *testing.T) {
+	router := gin.New()
+	router.PATCH("/users/:user_id", Update)
+
+	for _, contentType := range []string{"application/xml", "text/plain", "application/x-www-form-urlencoded", ""} {
+		t.Run(contentType, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(`<user><first_name>Ann</first_name></user>`))
+			if contentType != "" {
+				request.Header.Set("Content-Type", contentType)
+			}
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, request)
+
+			if response.Code != http.StatusUnsupportedMediaType {
+				t.Fatalf("status = %d, want %d", response.Code, http.StatusUnsupportedMediaType)
+			}
+			var restErr errors.RestErr
+			if err := json.Unmarshal(response.Body.Bytes(), &restErr); err != nil || restErr.Code != errors.CodeUnsupportedMediaType {
+				t.Errorf("body = %s, want code %s", response.Body.String(), errors.CodeUnsupportedMediaType)
+			}
+		})
+	}
+}
+
+func TestRequestContextActor(t *testing.T) {
+	tests := []struct {
+		name          string
+		authenticated string
+		callerID      string
+		wantActor     string
+		wantVerified  bool
+	}{
+		{"authenticated", "admin", "someone", "admin", true},
+		{"caller id", "", "billing-service", "billing-service", false},
+		{"nobody", "", "", "anonymous", false},
+		{"caller id too long", "", strings.Repeat("a", 100), strings.Repeat("a", audits.MaxActorLength), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/users", nil)
+			if test.callerID != "" {
+				c.Request.Header.Set("X-Caller-Id", test.callerID)
+			}
+			if test.authenticated != "" {
+				c.Set(audits.AuthenticatedActorKey, test.authenticated)
+			}
+
+			origin := audits.OriginFromContext(requestContext(c))
+			if origin.Actor != test.wantActor || origin.ActorVerified != test.wantVerified {
+				t.Errorf("actor = %q (verified %v), want %q (verified %v)", origin.Actor, origin.ActorVerified, test.wantActor, test.wantVerified)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package users
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/annazhao/bookstore_users_api/config"
+	"github.com/annazhao/bookstore_users_api/services"
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// when users.require_if_match is true, PUT, PATCH and DELETE are rejected without an If-Match header
+var requireIfMatch bool
+
+// Configure is used at startup to give the users endpoints their configuration
+func Configure(cfg config.UsersConfig) {
+	requireIfMatch = cfg.RequireIfMatch
+}
+
+// etag is the strong entity tag of a user at the given version, e.g. "3"
+func etag(version int64) string {
+	return fmt.Sprintf("%q", strconv.FormatInt(version, 10))
+}
+
+// setETag is used to tell the client which version of the user it has just received
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", etag(version))
+}
+
+// getIfMatchVersion returns the version the client expects from the If-Match header,
+// 0 means the client did not send one (or sent "*"), so any version is accepted.
+// If-Match can be a list of tags, it matches if one of them is the current version of the user
+func getIfMatchVersion(c *gin.Context, userID int64) (int64, *errors.RestErr) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" {
+		if requireIfMatch {
+			return 0, errors.NewPreconditionRequiredError(errors.CodeIfMatchRequired, "If-Match header is required")
+		}
+		return 0, nil
+	}
+	if ifMatch == "*" {
+		return 0, nil
+	}
+
+	versions, err := parseIfMatch(ifMatch)
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 1 {
+		return versions[0], nil
+	}
+	// the update is then only made if the user is still at the version that matched
+	user, err := services.UsersService.GetUser(requestContext(c), userID)
+	if err != nil {
+		return 0, err
+	}
+	for _, version := range versions {
+		if version == user.Version {
+			return version, nil
+		}
+	}
+	return 0, newIfMatchFailedError()
+}
+
+// parseIfMatch returns the versions of the strong tags of an If-Match list like "3", "4",
+// If-Match uses the strong comparison, so a weak tag like W/"3" never matches and is left out
+func parseIfMatch(ifMatch string) ([]int64, *errors.RestErr) {
+	versions := make([]int64, 0)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		version, err := strconv.Unquote(tag)
+		if err != nil || !strings.HasPrefix(tag, `"`) {
+			return nil, newIfMatchFailedError()
+		}
+		expectedVersion, err := strconv.ParseInt(version, 10, 64)
+		if err != nil || expectedVersion <= 0 {
+			return nil, newIfMatchFailedError()
+		}
+		versions = append(versions, expectedVersion)
+	}
+	if len(versions) == 0 {
+		return nil, newIfMatchFailedError()
+	}
+	return versions, nil
+}
+
+func newIfMatchFailedError() *errors.RestErr {
+	return errors.NewPreconditionFailedError(errors.CodeVersionMismatch, "If-Match does not match the current version of the user").WithMessageKey("VERSION_MISMATCH.if_match")
+}
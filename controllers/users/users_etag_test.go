@@ -0,0 +1,96 @@
+package users
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		name           string
+		ifMatch        string
+		requireIfMatch bool
+		wantVersion    int64
+		wantCode       string
+	}{
+		{"absent", "", false, 0, ""},
+		{"absent but required", "", true, 0, errors.CodeIfMatchRequired},
+		{"any version", "*", true, 0, ""},
+		{"strong tag", `"3"`, false, 3, ""},
+		{"strong tag with spaces", ` "12" `, false, 12, ""},
+		{"weak tag", `W/"3"`, false, 0, errors.CodeVersionMismatch},
+		{"unquoted", "3", false, 0, errors.CodeVersionMismatch},
+		{"not a number", `"abc"`, false, 0, errors.CodeVersionMismatch},
+		{"zero", `"0"`, false, 0, errors.CodeVersionMismatch},
+		{"negative", `"-1"`, false, 0, errors.CodeVersionMismatch},
+		{"weak and strong tags", `W/"2", "3"`, false, 3, ""},
+		{"only weak tags", `W/"2", W/"3"`, false, 0, errors.CodeVersionMismatch},
+		{"bad tag in a list", `"3", 4`, false, 0, errors.CodeVersionMismatch},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requireIfMatch = test.requireIfMatch
+			defer func() { requireIfMatch = false }()
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPut, "/users/1", nil)
+			if test.ifMatch != "" {
+				c.Request.Header.Set("If-Match", test.ifMatch)
+			}
+
+			version, err := getIfMatchVersion(c, 1)
+			if test.wantCode == "" {
+				if err != nil {
+					t.Fatalf("getIfMatchVersion() error = %v, want nil", err)
+				}
+				if version != test.wantVersion {
+					t.Errorf("getIfMatchVersion() = %d, want %d", version, test.wantVersion)
+				}
+				return
+			}
+			if err == nil || err.Code != test.wantCode {
+				t.Fatalf("getIfMatchVersion() error = %v, want %s", err, test.wantCode)
+			}
+		})
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		ifMatch  string
+		want     []int64
+		wantCode string
+	}{
+		{`"3"`, []int64{3}, ""},
+		{`"3", "4"`, []int64{3, 4}, ""},
+		{`"3","4" , "10"`, []int64{3, 4, 10}, ""},
+		{`W/"3", "4"`, []int64{4}, ""},
+		{`W/"3"`, nil, errors.CodeVersionMismatch},
+		{`"3", `, nil, errors.CodeVersionMismatch},
+		{`"3", "x"`, nil, errors.CodeVersionMismatch},
+		{"`3`", nil, errors.CodeVersionMismatch},
+	}
+	for _, test := range tests {
+		versions, err := parseIfMatch(test.ifMatch)
+		if test.wantCode != "" {
+			if err == nil || err.Code != test.wantCode {
+				t.Errorf("parseIfMatch(%s) error = %v, want %s", test.ifMatch, err, test.wantCode)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(versions, test.want) {
+			t.Errorf("parseIfMatch(%s) = %v, %v, want %v", test.ifMatch, versions, err, test.want)
+		}
+	}
+}
+
+func TestETag(t *testing.T) {
+	if got := etag(3); got != `"3"` {
+		t.Errorf("etag(3) = %s, want %s", got, `"3"`)
+	}
+}
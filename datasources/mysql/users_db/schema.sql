@@ -0,0 +1,89 @@
+-- tables used by the users api, run against the schema set in mysql_users_schema
+
+CREATE TABLE IF NOT EXISTS users (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  first_name VARCHAR(45) NULL,
+  last_name VARCHAR(45) NULL,
+  email VARCHAR(45) NOT NULL,
+  date_created DATETIME NOT NULL,
+  date_updated DATETIME NOT NULL,
+  status VARCHAR(45) NOT NULL,
+  password VARCHAR(32) NOT NULL,
+  version BIGINT NOT NULL DEFAULT 1,
+  PRIMARY KEY (id),
+  UNIQUE INDEX idx_users_email (email)
+);
+
+-- append-only audit trail, rows are never updated or deleted
+CREATE TABLE IF NOT EXISTS users_audit (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  actor VARCHAR(64) NOT NULL,
+  actor_verified BOOLEAN NOT NULL DEFAULT false,
+  action VARCHAR(32) NOT NULL,
+  target_user_id BIGINT NOT NULL,
+  changes JSON NOT NULL,
+  reason VARCHAR(255) NOT NULL DEFAULT '',
+  request_id VARCHAR(64) NOT NULL DEFAULT '', -- X-Request-Id, its length is checked by the request id middleware
+  ip VARCHAR(45) NOT NULL DEFAULT '',
+  date_created DATETIME NOT NULL,
+  PRIMARY KEY (id),
+  INDEX idx_users_audit_target_user_id (target_user_id),
+  INDEX idx_users_audit_actor (actor)
+);
+
+-- every revision of a user row, used for point-in-time history
+CREATE TABLE IF NOT EXISTS users_versions (
+  user_id BIGINT NOT NULL,
+  version BIGINT NOT NULL,
+  first_name VARCHAR(45) NULL,
+  last_name VARCHAR(45) NULL,
+  email VARCHAR(45) NOT NULL,
+  date_created DATETIME NOT NULL,
+  status VARCHAR(45) NOT NULL,
+  date_recorded DATETIME NOT NULL,
+  PRIMARY KEY (user_id, version),
+  INDEX idx_users_versions_date_recorded (user_id, date_recorded)
+);
+
+-- users created before versioning was added get their current state as first revision
+INSERT INTO users_versions(user_id, version, first_name, last_name, email, date_created, status, date_recorded)
+SELECT id, 1, first_name, last_name, email, date_created, status, date_created FROM users
+WHERE id NOT IN (SELECT DISTINCT user_id FROM users_versions);
+
+-- databases created before optimistic concurrency was added need the version column,
+-- starting from the last stored revision of each user:
+-- ALTER TABLE users ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+-- UPDATE users SET version = (SELECT COALESCE(MAX(v.version), 1) FROM users_versions v WHERE v.user_id = users.id);
+
+-- databases created before conditional requests were added need the date_updated column:
+-- ALTER TABLE users ADD COLUMN date_updated DATETIME NULL;
+-- UPDATE users SET date_updated = date_created;
+-- ALTER TABLE users MODIFY date_updated DATETIME NOT NULL;
+
+-- idempotency keys of unsafe requests with the response to replay, rows expire after users_idempotency_ttl
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  idempotency_key VARCHAR(255) NOT NULL,
+  scope VARCHAR(255) NOT NULL,
+  fingerprint CHAR(64) NOT NULL,
+  completed BOOLEAN NOT NULL DEFAULT false,
+  response_status INT NULL,
+  response_headers JSON NULL,
+  response_body MEDIUMBLOB NULL,
+  date_created DATETIME NOT NULL,
+  date_expires DATETIME NOT NULL,
+  date_lease_expires DATETIME NOT NULL,
+  PRIMARY KEY (idempotency_key, scope),
+  INDEX idx_idempotency_keys_date_expires (date_expires)
+);
+
+-- databases created before actors were marked as verified or not need the actor_verified column,
+-- the actors recorded before came from X-Caller-Id so they stay unverified:
+-- ALTER TABLE users_audit ADD COLUMN actor_verified BOOLEAN NOT NULL DEFAULT false;
+
+-- databases created before reservations had a lease need the date_lease_expires column:
+-- ALTER TABLE idempotency_keys ADD COLUMN date_lease_expires DATETIME NULL;
+-- UPDATE idempotency_keys SET date_lease_expires = date_created;
+-- ALTER TABLE idempotency_keys MODIFY date_lease_expires DATETIME NOT NULL;
+
+-- databases created before duplicate emails were rejected need the unique index (remove duplicates first):
+-- ALTER TABLE users ADD UNIQUE INDEX idx_users_email (email);
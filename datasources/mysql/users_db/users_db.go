@@ -1,50 +1,105 @@
 package usersdb
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
-	"os"
+	"sync"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
-)
-
-const (
-	mysqlUsersUsername = "mysql_users_username"
-	mysqlUsersPassword = "mysql_users_password"
-	mysqlUsersHost     = "mysql_users_host"
-	mysqlUsersSchema   = "mysql_users_schema"
+	"github.com/annazhao/bookstore_users_api/config"
+	"github.com/annazhao/bookstore_users_api/logger"
+	"github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
 )
 
 var (
-	Client   *sql.DB
-	username = os.Getenv(mysqlUsersUsername)
-	password = os.Getenv(mysqlUsersPassword)
-	host     = os.Getenv(mysqlUsersHost)
-	schema   = os.Getenv(mysqlUsersSchema)
+	Client *sql.DB
+
+	// ready is closed once the database has answered a ping
+	ready     = make(chan struct{})
+	readyOnce sync.Once
 )
 
-// should set up these environment variables in configuration files:
-// Run-->Open Configurations, then add env variables and values in "env": {}
-// then in terminal, export these env variables with the commands in mysql.txt file
-
-// connect to database
-func init() {
-	// username, password, host, schema
-	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8",
-		username,
-		password,
-		host,
-		schema)
-
-	var err error
-	Client, err = sql.Open("mysql", dataSourceName)
+// the database settings come from config.DatabaseConfig,
+// they can be set in the config file, with environment variables (see mysql.txt) or with flags
+
+// Open is used to create the connection pool with the given configuration,
+// it does not talk to the database yet, so it only fails if the configuration is wrong
+func Open(cfg config.DatabaseConfig) error {
+	mysqlConfig := mysql.NewConfig()
+	mysqlConfig.User = cfg.Username
+	mysqlConfig.Passwd = cfg.Password
+	mysqlConfig.Net = "tcp"
+	mysqlConfig.Addr = cfg.Host
+	mysqlConfig.DBName = cfg.Schema
+	mysqlConfig.Timeout = cfg.ConnectTimeout
+	mysqlConfig.ReadTimeout = cfg.ReadTimeout
+	mysqlConfig.WriteTimeout = cfg.WriteTimeout
+	mysqlConfig.Params = map[string]string{"charset": "utf8"}
+
+	client, err := sql.Open("mysql", mysqlConfig.FormatDSN())
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("error when trying to open database: %w", err)
+	}
+	client.SetMaxOpenConns(cfg.MaxOpenConns)
+	client.SetMaxIdleConns(cfg.MaxIdleConns)
+	client.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	client.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	Client = client
+	return nil
+}
+
+// Connect is used to wait until the database answers, it pings it again with an exponential backoff
+// until it works, ctx is done or cfg.ConnectAttempts is reached (0 means no limit).
+// Once connected IsReady is true, so the application can start without the database and get ready later
+func Connect(ctx context.Context, cfg config.DatabaseConfig) error {
+	if Client == nil {
+		if err := Open(cfg); err != nil {
+			return err
+		}
 	}
 
-	if err = Client.Ping(); err != nil {
-		panic(err)
+	backoff := cfg.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+		err := Client.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			readyOnce.Do(func() { close(ready) })
+			logger.Info("database connected", zap.Int("attempt", attempt))
+			return nil
+		}
+		if cfg.ConnectAttempts > 0 && attempt >= cfg.ConnectAttempts {
+			return fmt.Errorf("error when trying to connect to database after %d attempts: %w", attempt, err)
+		}
+
+		logger.Error("error when trying to connect to database, retrying", err, zap.Int("attempt", attempt), zap.Duration("backoff", backoff))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("error when trying to connect to database: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > cfg.RetryMaxBackoff {
+			backoff = cfg.RetryMaxBackoff
+		}
+	}
+}
+
+// IsReady tells if the database has been connected
+func IsReady() bool {
+	select {
+	case <-ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close is used on shutdown to close every connection of the pool
+func Close() error {
+	if Client == nil {
+		return nil
 	}
-	log.Println("database connected")
+	return Client.Close()
 }
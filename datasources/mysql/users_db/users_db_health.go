@@ -0,0 +1,74 @@
+package usersdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const queryGetColumns = "SELECT table_name, column_name FROM information_schema.columns WHERE table_schema=DATABASE();"
+
+// requiredColumns are the tables of schema.sql with the columns added by later migrations,
+// if one is missing the schema has not been migrated and the api would fail on it
+var requiredColumns = map[string][]string{
+	"users":            {"version", "date_updated"},
+	"users_audit":      {"request_id", "ip", "actor_verified"},
+	"users_versions":   {"date_recorded"},
+	"idempotency_keys": {"fingerprint", "date_expires", "date_lease_expires"},
+}
+
+// CheckConnected tells if Connect is done, until then Connect is still retrying with its backoff
+func CheckConnected(ctx context.Context) error {
+	if !IsReady() {
+		return errors.New("still connecting to the database")
+	}
+	return nil
+}
+
+// CheckConnection tells if the database answers a ping before ctx is done
+func CheckConnection(ctx context.Context) error {
+	if Client == nil {
+		return errors.New("database is not opened")
+	}
+	return Client.PingContext(ctx)
+}
+
+// CheckMigrations tells if every table and column the api needs exists in the schema
+func CheckMigrations(ctx context.Context) error {
+	if Client == nil {
+		return errors.New("database is not opened")
+	}
+	rows, err := Client.QueryContext(ctx, queryGetColumns)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return err
+		}
+		columns[strings.ToLower(table)+"."+strings.ToLower(column)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	missing := make([]string, 0)
+	for table, tableColumns := range requiredColumns {
+		for _, column := range tableColumns {
+			if !columns[table+"."+column] {
+				missing = append(missing, table+"."+column)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("schema is not migrated, missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package usersdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/annazhao/bookstore_users_api/metrics"
+	"github.com/annazhao/bookstore_users_api/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TrackQuery is used at the start of each DAO method with ctx, done := usersdb.TrackQuery(ctx, "get_user"); defer done(),
+// it starts the span of the statement, named after the query and never with its values,
+// the returned ctx carries that span so it must be given to the statement (e.g. stmt.QueryRowContext),
+// and the returned function ends the span and records the latency of the query
+func TrackQuery(ctx context.Context, name string) (context.Context, func()) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "sql "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "mysql"),
+			attribute.String("db.operation.name", name),
+		))
+	return ctx, func() {
+		span.End()
+		metrics.ObserveQuery(name, start)
+	}
+}
@@ -0,0 +1,44 @@
+package usersdb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/annazhao/bookstore_users_api/logger"
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+)
+
+// Executor is what the DAO methods need to run a statement,
+// both *sql.DB and *sql.Tx have this method so the same DAO method works inside and outside a transaction
+type Executor interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// WithTransaction runs fn inside a database transaction,
+// the transaction is committed if fn returns nil, otherwise it is rolled back.
+// begin, commit and rollback are tracked like the other queries, so they show up in the trace of the request
+func WithTransaction(ctx context.Context, fn func(tx Executor) *errors.RestErr) *errors.RestErr {
+	beginCtx, done := TrackQuery(ctx, "begin")
+	tx, err := Client.BeginTx(beginCtx, nil)
+	done()
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to begin transaction", err)
+	}
+
+	if restErr := fn(tx); restErr != nil {
+		_, done := TrackQuery(ctx, "rollback")
+		// the transaction is already rolled back if ctx is done
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			logger.ErrorContext(ctx, "error when trying to rollback transaction", err)
+		}
+		done()
+		return restErr
+	}
+
+	_, done = TrackQuery(ctx, "commit")
+	defer done()
+	if err := tx.Commit(); err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to commit transaction", err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package audits
+
+import "context"
+
+// Origin struct describes who made a request and from where, it is attached to every audit entry,
+// ActorVerified is false when the actor is only what the caller claims (e.g. the X-Caller-Id header)
+type Origin struct {
+	Actor         string
+	ActorVerified bool
+	RequestID     string
+	IP            string
+}
+
+// MaxActorLength is the length of the actor column of users_audit, a longer actor is cut to fit
+const MaxActorLength = 64
+
+// AuthenticatedActorKey is the gin context key where an authentication middleware puts the identity it verified
+const AuthenticatedActorKey = "authenticated_actor"
+
+type originKey struct{}
+
+// NewContext returns a copy of ctx which carries the origin of the request
+func NewContext(ctx context.Context, origin Origin) context.Context {
+	return context.WithValue(ctx, originKey{}, origin)
+}
+
+// OriginFromContext returns the origin stored in ctx, or an empty origin if there is none
+func OriginFromContext(ctx context.Context) Origin {
+	origin, _ := ctx.Value(originKey{}).(Origin)
+	return origin
+}
@@ -0,0 +1,95 @@
+package audits
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	usersdb "github.com/annazhao/bookstore_users_api/datasources/mysql/users_db"
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+)
+
+// the audit table is append-only: there is no update or delete query on purpose
+
+const (
+	queryInsertEntry = "INSERT INTO users_audit(actor, actor_verified, action, target_user_id, changes, reason, request_id, ip, date_created) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?);"
+	querySelectEntry = "SELECT id, actor, actor_verified, action, target_user_id, changes, reason, request_id, ip, date_created FROM users_audit"
+)
+
+// Save method is used to save the audit entry, pass the same transaction used for the change itself
+func (entry *Entry) Save(ctx context.Context, exec usersdb.Executor) *errors.RestErr {
+	ctx, done := usersdb.TrackQuery(ctx, "insert_audit_entry")
+	defer done()
+	changesJSON, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to marshal audit changes", err)
+	}
+
+	stmt, err := exec.PrepareContext(ctx, queryInsertEntry)
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare save audit entry statement", err)
+	}
+	defer stmt.Close()
+
+	insertResult, err := stmt.ExecContext(ctx, entry.Actor, entry.ActorVerified, entry.Action, entry.TargetUserID, string(changesJSON), entry.Reason, entry.RequestID, entry.IP, entry.DateCreated)
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to save audit entry", err)
+	}
+
+	entryID, err := insertResult.LastInsertId()
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to get last insert id after saving audit entry", err)
+	}
+	entry.ID = entryID
+	return nil
+}
+
+// Search method is used to find audit entries by target user and/or actor, newest first
+func (entries *Entries) Search(ctx context.Context, targetUserID int64, actor string, limit int) *errors.RestErr {
+	ctx, done := usersdb.TrackQuery(ctx, "search_audit_entries")
+	defer done()
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	if targetUserID > 0 {
+		conditions = append(conditions, "target_user_id=?")
+		args = append(args, targetUserID)
+	}
+	if actor != "" {
+		conditions = append(conditions, "actor=?")
+		args = append(args, actor)
+	}
+	args = append(args, limit)
+
+	query := querySelectEntry
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?;"
+
+	stmt, err := usersdb.Client.PrepareContext(ctx, query)
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare search audit entries statement", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to search audit entries", err)
+	}
+	defer rows.Close()
+
+	results := make(Entries, 0)
+	for rows.Next() {
+		var entry Entry
+		var changesJSON string
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.ActorVerified, &entry.Action, &entry.TargetUserID, &changesJSON, &entry.Reason, &entry.RequestID, &entry.IP, &entry.DateCreated); err != nil {
+			return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to scan audit row into entry struct", err)
+		}
+		if err := json.Unmarshal([]byte(changesJSON), &entry.Changes); err != nil {
+			return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to unmarshal audit changes", err)
+		}
+		results = append(results, entry)
+	}
+	*entries = results
+	return nil
+}
@@ -0,0 +1,87 @@
+package audits
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/annazhao/bookstore_users_api/utils/dates"
+)
+
+// these are the actions we record in the audit trail
+const (
+	ActionCreate       = "create"
+	ActionUpdate       = "update"
+	ActionDelete       = "delete"
+	ActionStatusChange = "status_change"
+)
+
+// Entry struct is one append-only record in the audit trail of a user
+type Entry struct {
+	ID    int64  `json:"id"`
+	Actor string `json:"actor"`
+	// ActorVerified tells if the actor was authenticated, or only claimed by the caller
+	ActorVerified bool              `json:"actor_verified"`
+	Action        string            `json:"action"`
+	TargetUserID  int64             `json:"target_user_id"`
+	Changes       map[string]Change `json:"changes"`
+	Reason        string            `json:"reason,omitempty"`
+	RequestID     string            `json:"request_id"`
+	IP            string            `json:"ip"`
+	DateCreated   string            `json:"date_created"`
+}
+
+// Change struct holds the value of one field before and after the change
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Entries is the type of a slice of Entry
+type Entries []Entry
+
+// NewEntry is used to create an audit entry for the given action,
+// before and after are the representations of the user before and after the change (nil if there is none)
+func NewEntry(ctx context.Context, action string, targetUserID int64, before interface{}, after interface{}) *Entry {
+	origin := OriginFromContext(ctx)
+	return &Entry{
+		Actor:         origin.Actor,
+		ActorVerified: origin.ActorVerified,
+		Action:        action,
+		TargetUserID:  targetUserID,
+		Changes:       Diff(before, after),
+		RequestID:     origin.RequestID,
+		IP:            origin.IP,
+		DateCreated:   dates.GetNowDBFormat(),
+	}
+}
+
+// Diff returns only the fields whose value is different between before and after,
+// both of them are converted through their JSON representation so any struct can be compared
+func Diff(before interface{}, after interface{}) map[string]Change {
+	beforeFields := toFields(before)
+	afterFields := toFields(after)
+
+	changes := make(map[string]Change)
+	for field, beforeValue := range beforeFields {
+		if afterValue, ok := afterFields[field]; !ok || !reflect.DeepEqual(beforeValue, afterValue) {
+			changes[field] = Change{Before: beforeValue, After: afterFields[field]}
+		}
+	}
+	for field, afterValue := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			changes[field] = Change{Before: nil, After: afterValue}
+		}
+	}
+	return changes
+}
+
+func toFields(value interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if value == nil {
+		return fields
+	}
+	valueJSON, _ := json.Marshal(value)
+	json.Unmarshal(valueJSON, &fields)
+	return fields
+}
@@ -0,0 +1,77 @@
+package audits
+
+import (
+	"reflect"
+	"testing"
+)
+
+type profile struct {
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Status string `json:"status,omitempty"`
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before interface{}
+		after  interface{}
+		want   map[string]Change
+	}{
+		{
+			name:   "nothing changed",
+			before: profile{Name: "ann", Email: "ann@example.com"},
+			after:  profile{Name: "ann", Email: "ann@example.com"},
+			want:   map[string]Change{},
+		},
+		{
+			name:   "one field changed",
+			before: profile{Name: "ann", Email: "ann@example.com"},
+			after:  profile{Name: "ann", Email: "anna@example.com"},
+			want: map[string]Change{
+				"email": {Before: "ann@example.com", After: "anna@example.com"},
+			},
+		},
+		{
+			name:   "field added",
+			before: profile{Name: "ann"},
+			after:  profile{Name: "ann", Status: "active"},
+			want: map[string]Change{
+				"status": {Before: nil, After: "active"},
+			},
+		},
+		{
+			name:   "field removed",
+			before: profile{Name: "ann", Status: "active"},
+			after:  profile{Name: "ann"},
+			want: map[string]Change{
+				"status": {Before: "active", After: nil},
+			},
+		},
+		{
+			name:   "created",
+			before: nil,
+			after:  profile{Name: "ann", Email: "ann@example.com"},
+			want: map[string]Change{
+				"name":  {Before: nil, After: "ann"},
+				"email": {Before: nil, After: "ann@example.com"},
+			},
+		},
+		{
+			name:   "deleted",
+			before: profile{Name: "ann", Email: "ann@example.com"},
+			after:  nil,
+			want: map[string]Change{
+				"name":  {Before: "ann", After: nil},
+				"email": {Before: "ann@example.com", After: nil},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Diff(test.before, test.after); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Diff() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	usersdb "github.com/annazhao/bookstore_users_api/datasources/mysql/users_db"
+	"github.com/annazhao/bookstore_users_api/utils/dates"
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"github.com/annazhao/bookstore_users_api/utils/mysqls"
+)
+
+const (
+	queryInsertRecord   = "INSERT INTO idempotency_keys(idempotency_key, scope, fingerprint, completed, date_created, date_expires, date_lease_expires) VALUES(?, ?, ?, false, ?, ?, ?);"
+	queryGetRecord      = "SELECT fingerprint, completed, response_status, response_headers, response_body, date_created, date_expires FROM idempotency_keys WHERE idempotency_key=? AND scope=?;"
+	queryCompleteRecord = "UPDATE idempotency_keys SET completed=true, response_status=?, response_headers=?, response_body=? WHERE idempotency_key=? AND scope=?;"
+	queryDeleteRecord   = "DELETE FROM idempotency_keys WHERE idempotency_key=? AND scope=?;"
+	queryDeleteExpired  = "DELETE FROM idempotency_keys WHERE idempotency_key=? AND scope=? AND (date_expires<=? OR (completed=false AND date_lease_expires<=?));"
+)
+
+// Reserve method is used to claim the key before the request is handled,
+// if the key is already taken, the stored record is returned instead so the caller can replay or reject it.
+// The reservation is a lease: if the request is not finished before it ends (e.g. the process died),
+// the key can be claimed again instead of staying in progress until it expires
+func (record *Record) Reserve(ctx context.Context, ttl time.Duration, lease time.Duration) (*Record, *errors.RestErr) {
+	ctx, done := usersdb.TrackQuery(ctx, "reserve_idempotency_key")
+	defer done()
+	now := dates.GetNow()
+	record.DateCreated = dates.ToDBFormat(now)
+	record.DateExpires = dates.ToDBFormat(now.Add(ttl))
+	record.DateLeaseExpires = dates.ToDBFormat(now.Add(lease))
+
+	// an expired key, or a key still in progress after its lease, is free again
+	deleteStmt, err := usersdb.Client.PrepareContext(ctx, queryDeleteExpired)
+	if err != nil {
+		return nil, errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare delete expired idempotency key statement", err)
+	}
+	defer deleteStmt.Close()
+
+	if _, err := deleteStmt.ExecContext(ctx, record.Key, record.Scope, record.DateCreated, record.DateCreated); err != nil {
+		return nil, errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to delete expired idempotency key", err)
+	}
+
+	stmt, err := usersdb.Client.PrepareContext(ctx, queryInsertRecord)
+	if err != nil {
+		return nil, errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare save idempotency key statement", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, record.Key, record.Scope, record.Fingerprint, record.DateCreated, record.DateExpires, record.DateLeaseExpires)
+	if err == nil {
+		return nil, nil
+	}
+	if !mysqls.IsDuplicateEntry(err) {
+		return nil, errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to save idempotency key", err)
+	}
+
+	existing := &Record{Key: record.Key, Scope: record.Scope}
+	if err := existing.Get(ctx); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// Get method is used to retrieve the record by key and scope from database
+func (record *Record) Get(ctx context.Context) *errors.RestErr {
+	ctx, done := usersdb.TrackQuery(ctx, "get_idempotency_key")
+	defer done()
+	stmt, err := usersdb.Client.PrepareContext(ctx, queryGetRecord)
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare get idempotency key statement", err)
+	}
+	defer stmt.Close()
+
+	// response columns are null until the first request is completed
+	var status sql.NullInt64
+	var headersJSON, body []byte
+	result := stmt.QueryRowContext(ctx, record.Key, record.Scope)
+	if err := result.Scan(&record.Fingerprint, &record.Completed, &status, &headersJSON, &body, &record.DateCreated, &record.DateExpires); err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to get idempotency key", err)
+	}
+
+	record.ResponseStatus = int(status.Int64)
+	record.ResponseBody = body
+	if len(headersJSON) > 0 {
+		if err := json.Unmarshal(headersJSON, &record.ResponseHeaders); err != nil {
+			return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to unmarshal idempotency response headers", err)
+		}
+	}
+	return nil
+}
+
+// Complete method is used to store the response of the request, so it can be replayed for the same key
+func (record *Record) Complete(ctx context.Context) *errors.RestErr {
+	ctx, done := usersdb.TrackQuery(ctx, "complete_idempotency_key")
+	defer done()
+	headersJSON, err := json.Marshal(record.ResponseHeaders)
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to marshal idempotency response headers", err)
+	}
+
+	stmt, err := usersdb.Client.PrepareContext(ctx, queryCompleteRecord)
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare complete idempotency key statement", err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, record.ResponseStatus, string(headersJSON), record.ResponseBody, record.Key, record.Scope); err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to complete idempotency key", err)
+	}
+	record.Completed = true
+	return nil
+}
+
+// Release method is used to free the key when the request failed on our side, so the client can retry it
+func (record *Record) Release(ctx context.Context) *errors.RestErr {
+	ctx, done := usersdb.TrackQuery(ctx, "release_idempotency_key")
+	defer done()
+	stmt, err := usersdb.Client.PrepareContext(ctx, queryDeleteRecord)
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare release idempotency key statement", err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, record.Key, record.Scope); err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to release idempotency key", err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+// FingerprintHeaders are the request headers that change the response, X-Public hides the private fields of the user
+var FingerprintHeaders = []string{"X-Public"}
+
+// Record struct is a stored idempotency key with the response of the first request made with it
+type Record struct {
+	Key             string
+	Scope           string // method and path, the same key can be used on different endpoints
+	Fingerprint     string
+	Completed       bool
+	ResponseStatus  int
+	ResponseHeaders http.Header
+	ResponseBody    []byte
+	DateCreated     string
+	DateExpires     string
+	// DateLeaseExpires is when a reservation still in progress is considered abandoned
+	DateLeaseExpires string
+}
+
+// GetFingerprint returns the fingerprint of a request, two requests with the same key must have the same fingerprint,
+// headers are the request headers the response depends on (e.g. X-Public), so a replay never answers another representation
+func GetFingerprint(scope string, headers http.Header, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(scope))
+	hash.Write([]byte{0})
+	for _, name := range FingerprintHeaders {
+		hash.Write([]byte(name + ": " + headers.Get(name)))
+		hash.Write([]byte{0})
+	}
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,32 @@
+package idempotency
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestGetFingerprint(t *testing.T) {
+	base := GetFingerprint("POST /users", http.Header{}, []byte(`{"email":"ann@example.com"}`))
+
+	tests := []struct {
+		name     string
+		scope    string
+		headers  http.Header
+		body     string
+		wantSame bool
+	}{
+		{"same request", "POST /users", http.Header{}, `{"email":"ann@example.com"}`, true},
+		{"another request id", "POST /users", http.Header{"X-Request-Id": {"abc"}}, `{"email":"ann@example.com"}`, true},
+		{"public representation", "POST /users", http.Header{"X-Public": {"true"}}, `{"email":"ann@example.com"}`, false},
+		{"another body", "POST /users", http.Header{}, `{"email":"bob@example.com"}`, false},
+		{"another endpoint", "POST /users/login", http.Header{}, `{"email":"ann@example.com"}`, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := GetFingerprint(test.scope, test.headers, []byte(test.body))
+			if same := got == base; same != test.wantSame {
+				t.Errorf("same fingerprint = %v, want %v", same, test.wantSame)
+			}
+		})
+	}
+}
@@ -1,11 +1,11 @@
 package users
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"strings"
 
 	usersdb "github.com/annazhao/bookstore_users_api/datasources/mysql/users_db"
-	"github.com/annazhao/bookstore_users_api/logger"
 	"github.com/annazhao/bookstore_users_api/utils/errors"
 	"github.com/annazhao/bookstore_users_api/utils/mysqls"
 )
@@ -13,147 +13,204 @@ import (
 // here we will have the access layer to our database
 
 const (
-	queryInsertUser             = "INSERT INTO users(first_name, last_name, email, date_created, status, password) VALUES(?, ?, ?, ?, ?, ?);"
-	queryGetUser                = "SELECT id, first_name, last_name, email, date_created, status FROM users WHERE id=?;"
-	queryUpdateUser             = "UPDATE users SET first_name=?, last_name=?, email=? WHERE id=?;"
-	queryDeleteUser             = "DELETE FROM users WHERE id=?;"
-	queryFindByStatus           = "SELECT id, first_name, last_name, email, date_created, status FROM users WHERE status=?;"
-	queryFindByEmailAndPassword = "SELECT id, first_name, last_name, email, date_created, status FROM users WHERE email=? AND password=? AND status=?;"
+	queryInsertUser             = "INSERT INTO users(first_name, last_name, email, date_created, date_updated, status, password, version) VALUES(?, ?, ?, ?, ?, ?, ?, ?);"
+	queryGetUser                = "SELECT id, first_name, last_name, email, date_created, date_updated, status, version FROM users WHERE id=?;"
+	queryUpdateUser             = "UPDATE users SET first_name=?, last_name=?, email=?, date_updated=?, version=version+1 WHERE id=? AND version=?;"
+	queryUpdateStatus           = "UPDATE users SET status=?, date_updated=?, version=version+1 WHERE id=? AND version=?;"
+	queryExistsUser             = "SELECT id FROM users WHERE id=?;"
+	queryFindByStatus           = "SELECT id, first_name, last_name, email, date_created, date_updated, status, version FROM users WHERE status=?;"
+	queryFindByEmailAndPassword = "SELECT id, first_name, last_name, email, date_created, date_updated, status, version FROM users WHERE email=? AND password=? AND status=?;"
 )
 
+// firstVersion is the version of a newly created user, every update adds one to it
+const firstVersion = 1
+
 // Get method is used to retrieve the user by ID from database
-func (user *User) Get() *errors.RestErr {
-	stmt, err := usersdb.Client.Prepare(queryGetUser)
+func (user *User) Get(ctx context.Context) *errors.RestErr {
+	ctx, done := usersdb.TrackQuery(ctx, "get_user")
+	defer done()
+	stmt, err := usersdb.Client.PrepareContext(ctx, queryGetUser)
 	if err != nil {
-		logger.Error("error when trying to prepare get user statement", err)
-		return errors.NewInternalServerError("database error")
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare get user statement", err)
 		// this is a simple error description given back to user
 	}
 	defer stmt.Close()
 
 	// QueryRow only get back 1 row from the result dataset
 	// Query will get back *Rows, if using stmt.Query(user.ID), we need add defer result.Close()
-	result := stmt.QueryRow(user.ID)
-	if getErr := result.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.DateCreated, &user.Status); getErr != nil {
-		logger.Error("error when trying to get user by id", getErr)
-		return errors.NewInternalServerError("database error")
-		// return mysqls.ParseError(getErr)
+	result := stmt.QueryRowContext(ctx, user.ID)
+	if getErr := result.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.DateCreated, &user.DateUpdated, &user.Status, &user.Version); getErr != nil {
+		if mysqls.IsNoRows(getErr) {
+			return newUserNotFoundError(user.ID)
+		}
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to get user by id", getErr)
 	}
 	return nil
 }
 
-// Save method is used to save the user into the database
-func (user *User) Save() *errors.RestErr {
+// Save method is used to save the user into the database,
+// exec can be usersdb.Client or a transaction so the change can be saved together with its audit entry
+func (user *User) Save(ctx context.Context, exec usersdb.Executor) *errors.RestErr {
+	ctx, done := usersdb.TrackQuery(ctx, "insert_user")
+	defer done()
 
-	stmt, err := usersdb.Client.Prepare(queryInsertUser)
+	stmt, err := exec.PrepareContext(ctx, queryInsertUser)
 	if err != nil {
-		logger.Error("error when trying to prepare save user statement", err)
-		return errors.NewInternalServerError("database error")
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare save user statement", err)
 	}
 	defer stmt.Close() // this is very important
 
-	insertResult, saveErr := stmt.Exec(user.FirstName, user.LastName, user.Email, user.DateCreated, user.Status, user.Password)
+	user.Version = firstVersion
+	insertResult, saveErr := stmt.ExecContext(ctx, user.FirstName, user.LastName, user.Email, user.DateCreated, user.DateUpdated, user.Status, user.Password, user.Version)
 	if saveErr != nil {
-		logger.Error("error when trying to save user", saveErr)
-		return errors.NewInternalServerError("database error")
-		// return mysqls.ParseError(saveErr)
+		// email has a unique index, so a duplicate email is reported by mysql
+		if mysqls.IsDuplicateEntry(saveErr) {
+			return newEmailTakenError(user.Email)
+		}
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to save user", saveErr)
 	}
 
 	userID, err := insertResult.LastInsertId()
 	if err != nil {
-		logger.Error("error when trying to get last insert id after creating a new user", err)
-		return errors.NewInternalServerError("database error")
-		// return mysqls.ParseError(err)
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to get last insert id after creating a new user", err)
 	}
 	user.ID = userID
 	return nil
 }
 
-// Update method is used to update the user in the database
-func (user *User) Update() *errors.RestErr {
-	stmt, err := usersdb.Client.Prepare(queryUpdateUser)
+// Update method is used to update the user in the database,
+// it only succeeds if the row is still at user.Version, then user.Version is moved to the new version
+func (user *User) Update(ctx context.Context, exec usersdb.Executor) *errors.RestErr {
+	ctx, done := usersdb.TrackQuery(ctx, "update_user")
+	defer done()
+	stmt, err := exec.PrepareContext(ctx, queryUpdateUser)
 	if err != nil {
-		logger.Error("error when trying to prepare update user statement", err)
-		return errors.NewInternalServerError("database error")
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare update user statement", err)
 		// return errors.NewInternalServerError(err.Error())
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(user.FirstName, user.LastName, user.Email, user.ID)
+	updateResult, err := stmt.ExecContext(ctx, user.FirstName, user.LastName, user.Email, user.DateUpdated, user.ID, user.Version)
 	if err != nil {
-		logger.Error("error when trying to update user", err)
-		return errors.NewInternalServerError("database error")
-		// return mysqls.ParseError(err)
+		if mysqls.IsDuplicateEntry(err) {
+			return newEmailTakenError(user.Email)
+		}
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to update user", err)
+	}
+	if err := user.checkWritten(ctx, exec, updateResult); err != nil {
+		return err
 	}
+	user.Version++
 	return nil
 }
 
-// Delete method is used to update the user in the database
-func (user *User) Delete() *errors.RestErr {
-	stmt, err := usersdb.Client.Prepare(queryDeleteUser)
+// UpdateStatus method is used to update only the status of the user in the database
+func (user *User) UpdateStatus(ctx context.Context, exec usersdb.Executor) *errors.RestErr {
+	ctx, done := usersdb.TrackQuery(ctx, "update_user_status")
+	defer done()
+	stmt, err := exec.PrepareContext(ctx, queryUpdateStatus)
 	if err != nil {
-		logger.Error("error when trying to prepare delete user statement", err)
-		return errors.NewInternalServerError("database error")
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare update user status statement", err)
 	}
 	defer stmt.Close()
 
-	if _, err = stmt.Exec(user.ID); err != nil {
-		logger.Error("error when trying to delete user", err)
-		return errors.NewInternalServerError("database error")
-		// return mysqls.ParseError(err)
+	updateResult, err := stmt.ExecContext(ctx, user.Status, user.DateUpdated, user.ID, user.Version)
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to update user status", err)
 	}
+	if err := user.checkWritten(ctx, exec, updateResult); err != nil {
+		return err
+	}
+	user.Version++
 	return nil
 }
 
+// newEmailTakenError is returned when another user already has the email
+func newEmailTakenError(email string) *errors.RestErr {
+	return errors.NewConflictError(errors.CodeEmailTaken, fmt.Sprintf("email %s is already registered", email)).WithParam("email", email)
+}
+
+// newUserNotFoundError is returned when there is no user with the id
+func newUserNotFoundError(userID int64) *errors.RestErr {
+	return errors.NewNotFoundError(errors.CodeUserNotFound, fmt.Sprintf("user %d not found", userID)).WithParam("user_id", userID)
+}
+
+// checkWritten returns an error if the write did not touch any row:
+// not found if the user does not exist (anymore), precondition failed if another request changed its version
+func (user *User) checkWritten(ctx context.Context, exec usersdb.Executor, result sql.Result) *errors.RestErr {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to get rows affected", err)
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	ctx, done := usersdb.TrackQuery(ctx, "exists_user")
+	defer done()
+	stmt, err := exec.PrepareContext(ctx, queryExistsUser)
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare exists user statement", err)
+	}
+	defer stmt.Close()
+
+	var userID int64
+	if err := stmt.QueryRowContext(ctx, user.ID).Scan(&userID); err != nil {
+		if mysqls.IsNoRows(err) {
+			return newUserNotFoundError(user.ID)
+		}
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to check if user exists", err)
+	}
+	return errors.NewPreconditionFailedError(errors.CodeVersionMismatch, "user has been modified by another request")
+}
+
 // FindByStatus method is used to find users from the database based on status
-func (user *User) FindByStatus(status string) ([]User, *errors.RestErr) {
-	stmt, err := usersdb.Client.Prepare(queryFindByStatus)
+func (user *User) FindByStatus(ctx context.Context, status string) ([]User, *errors.RestErr) {
+	ctx, done := usersdb.TrackQuery(ctx, "find_users_by_status")
+	defer done()
+	stmt, err := usersdb.Client.PrepareContext(ctx, queryFindByStatus)
 	if err != nil {
-		logger.Error("error when trying to prepare find user by status statement", err)
-		return nil, errors.NewInternalServerError("database error")
+		return nil, errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare find user by status statement", err)
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query(status)
+	rows, err := stmt.QueryContext(ctx, status)
 	if err != nil {
-		logger.Error("error when trying to find user by status", err)
-		return nil, errors.NewInternalServerError("database error")
+		return nil, errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to find user by status", err)
 	}
 	defer rows.Close()
 
 	results := make([]User, 0)
 	for rows.Next() {
 		var user User
-		if err := rows.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.DateCreated, &user.Status); err != nil {
-			logger.Error("error when trying to scan user row into user struct", err)
-			return nil, errors.NewInternalServerError("database error")
-			// return nil, mysqls.ParseError(err)
+		if err := rows.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.DateCreated, &user.DateUpdated, &user.Status, &user.Version); err != nil {
+			return nil, errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to scan user row into user struct", err)
 		}
 		results = append(results, user)
 	}
 
 	if len(results) == 0 {
-		return nil, errors.NewNotFoundError(fmt.Sprintf("no users matching status %s", status))
+		return nil, errors.NewNotFoundError(errors.CodeNoUsersFound, fmt.Sprintf("no users matching status %s", status)).WithParam("status", status)
 	}
 	return results, nil
 }
 
 // FindByEmailAndPassword method is used to retrieve the user by email and password for oauth api from database
-func (user *User) FindByEmailAndPassword() *errors.RestErr {
-	stmt, err := usersdb.Client.Prepare(queryFindByEmailAndPassword)
+func (user *User) FindByEmailAndPassword(ctx context.Context) *errors.RestErr {
+	ctx, done := usersdb.TrackQuery(ctx, "find_user_by_email_and_password")
+	defer done()
+	stmt, err := usersdb.Client.PrepareContext(ctx, queryFindByEmailAndPassword)
 	if err != nil {
-		logger.Error("error when trying to prepare find user by email and password statement", err)
-		return errors.NewInternalServerError("database error")
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare find user by email and password statement", err)
 	}
 	defer stmt.Close()
 
-	result := stmt.QueryRow(user.Email, user.Password, StatusActive)
-	if getErr := result.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.DateCreated, &user.Status); getErr != nil {
-		if strings.Contains(getErr.Error(), mysqls.ErrorNoRows) {
-			return errors.NewNotFoundError("invalid user credentials")
+	result := stmt.QueryRowContext(ctx, user.Email, user.Password, StatusActive)
+	if getErr := result.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.DateCreated, &user.DateUpdated, &user.Status, &user.Version); getErr != nil {
+		if mysqls.IsNoRows(getErr) {
+			return errors.NewNotFoundError(errors.CodeInvalidCredentials, "invalid user credentials")
 		}
-		logger.Error("error when trying to get user by email and password", getErr)
-		return errors.NewInternalServerError("database error")
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to get user by email and password", getErr)
 	}
 	return nil
 }
@@ -6,9 +6,6 @@ import (
 	"github.com/annazhao/bookstore_users_api/utils/errors"
 )
 
-// StatusActive is the default status for newly created users which is active
-const StatusActive = "active"
-
 // User struct contains all the fields for a type of user
 // password field is an internal field, we don't want it to work with json
 type User struct {
@@ -17,25 +14,43 @@ type User struct {
 	LastName    string `json:"last_name"`
 	Email       string `json:"email"`
 	DateCreated string `json:"date_created"`
+	DateUpdated string `json:"date_updated"`
 	Status      string `json:"status"`
 	Password    string `json:"password"`
+	Version     int64  `json:"version"`
 }
 
 // Users is the type of a slice of User
 type Users []User
 
-// Validate method is to validate if the user's email is not empty for now
+// Validate method is to validate a new user, every invalid field is reported in the causes of the error
 func (user *User) Validate() *errors.RestErr {
+	user.trim()
+	user.Password = strings.TrimSpace(user.Password)
+
+	v := &validator{}
+	user.validateProfile(v)
+	v.checkPassword(user.Password)
+	return v.err()
+}
+
+// ValidateProfile method is to validate only the fields a user can change after creation, e.g. with a patch
+func (user *User) ValidateProfile() *errors.RestErr {
+	user.trim()
+
+	v := &validator{}
+	user.validateProfile(v)
+	return v.err()
+}
+
+func (user *User) trim() {
 	user.FirstName = strings.TrimSpace(user.FirstName)
 	user.LastName = strings.TrimSpace(user.LastName)
-
 	user.Email = strings.TrimSpace(strings.ToLower(user.Email))
-	if user.Email == "" {
-		return errors.NewBadRequestError("invalid email address")
-	}
-	user.Password = strings.TrimSpace(user.Password)
-	if user.Password == "" {
-		return errors.NewBadRequestError("invalid password")
-	}
-	return nil
+}
+
+func (user *User) validateProfile(v *validator) {
+	v.checkName("first_name", user.FirstName)
+	v.checkName("last_name", user.LastName)
+	v.checkEmail(user.Email)
 }
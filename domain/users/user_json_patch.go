@@ -0,0 +1,113 @@
+package users
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+)
+
+// JSONPatchContentType is the content type of a JSON Patch request (RFC 6902)
+const JSONPatchContentType = "application/json-patch+json"
+
+// JSONPatch is a JSON Patch document: a list of operations applied in order,
+// if one of them fails (including a "test") none of them is applied
+type JSONPatch json.RawMessage
+
+// PatchOperation is one operation of a JSON Patch, only add, remove, replace and test are supported
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Apply method is used to apply every operation of the JSON Patch on the user,
+// the operations work on a copy, so the user is only changed if all of them succeed
+func (patch JSONPatch) Apply(user *User) *errors.RestErr {
+	var operations []PatchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil || operations == nil {
+		return errors.NewBadRequestError(errors.CodeInvalidPatch, "json patch should be an array of operations").WithMessageKey("INVALID_PATCH.json_not_array")
+	}
+
+	document, err := toDocument(user)
+	if err != nil {
+		return err
+	}
+	for index, operation := range operations {
+		if err := operation.apply(document); err != nil {
+			err.Message = fmt.Sprintf("operation %d: %s", index, err.Message)
+			err.WithParam("index", index)
+			return err
+		}
+	}
+	return fromDocument(document, user)
+}
+
+func (operation PatchOperation) apply(document map[string]interface{}) *errors.RestErr {
+	field, err := getPatchField(operation.Path)
+	if err != nil {
+		return err
+	}
+
+	switch operation.Op {
+	case "add", "replace":
+		value, err := operation.getValue()
+		if err != nil {
+			return err
+		}
+		// every user field always exists in the document, so add and replace both just set the value
+		document[field] = value
+	case "remove":
+		if _, ok := document[field]; !ok {
+			return errors.NewBadRequestError(errors.CodeInvalidPatch, fmt.Sprintf("path %s does not exist", operation.Path)).
+				WithMessageKey("INVALID_PATCH.path_missing").WithParam("path", operation.Path)
+		}
+		delete(document, field)
+	case "test":
+		value, err := operation.getValue()
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(document[field], value) {
+			return errors.NewConflictError(errors.CodePatchTestFailed, fmt.Sprintf("test failed for path %s", operation.Path)).
+				WithParam("path", operation.Path)
+		}
+	default:
+		return errors.NewBadRequestError(errors.CodeInvalidPatch, fmt.Sprintf("unsupported operation %s", operation.Op)).
+			WithMessageKey("INVALID_PATCH.unsupported_op").WithParam("op", operation.Op)
+	}
+	return nil
+}
+
+// getValue returns the decoded value of the operation, it is required for add, replace and test
+func (operation PatchOperation) getValue() (interface{}, *errors.RestErr) {
+	if operation.Value == nil {
+		return nil, errors.NewBadRequestError(errors.CodeInvalidPatch, fmt.Sprintf("value is required for %s", operation.Op)).
+			WithMessageKey("INVALID_PATCH.value_required").WithParam("op", operation.Op)
+	}
+	// UseNumber so the value compares equal to the numbers in the document
+	decoder := json.NewDecoder(bytes.NewReader(operation.Value))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, errors.NewBadRequestError(errors.CodeInvalidPatch, "invalid value").WithMessageKey("INVALID_PATCH.invalid_value")
+	}
+	return value, nil
+}
+
+// getPatchField returns the user field a JSON Pointer like "/first_name" points to, only mutable fields are allowed
+func getPatchField(path string) (string, *errors.RestErr) {
+	if !strings.HasPrefix(path, "/") {
+		return "", errors.NewBadRequestError(errors.CodeInvalidPatch, fmt.Sprintf("invalid path %s", path)).
+			WithMessageKey("INVALID_PATCH.invalid_path").WithParam("path", path)
+	}
+	field := strings.NewReplacer("~1", "/", "~0", "~").Replace(strings.TrimPrefix(path, "/"))
+	if !mutableFields[field] {
+		return "", errors.NewBadRequestError(errors.CodeInvalidPatch, fmt.Sprintf("path %s cannot be changed", path)).
+			WithMessageKey("INVALID_PATCH.read_only_path").WithParam("path", path)
+	}
+	return field, nil
+}
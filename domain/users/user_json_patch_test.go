@@ -0,0 +1,98 @@
+package users
+
+import (
+	"testing"
+
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+)
+
+func TestJSONPatchApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		want     User
+		wantCode string
+	}{
+		{
+			name:  "replace",
+			patch: `[{"op": "replace", "path": "/first_name", "value": "Anna"}]`,
+			want:  User{ID: 1, FirstName: "Anna", LastName: "Zhao", Email: "ann@example.com", Status: StatusActive, Version: 2},
+		},
+		{
+			name:  "add",
+			patch: `[{"op": "add", "path": "/email", "value": "anna@example.com"}]`,
+			want:  User{ID: 1, FirstName: "Ann", LastName: "Zhao", Email: "anna@example.com", Status: StatusActive, Version: 2},
+		},
+		{
+			name:  "remove",
+			patch: `[{"op": "remove", "path": "/last_name"}]`,
+			want:  User{ID: 1, FirstName: "Ann", LastName: "", Email: "ann@example.com", Status: StatusActive, Version: 2},
+		},
+		{
+			name:  "test then replace",
+			patch: `[{"op": "test", "path": "/email", "value": "ann@example.com"}, {"op": "replace", "path": "/email", "value": "anna@example.com"}]`,
+			want:  User{ID: 1, FirstName: "Ann", LastName: "Zhao", Email: "anna@example.com", Status: StatusActive, Version: 2},
+		},
+		{
+			name:     "failed test applies nothing",
+			patch:    `[{"op": "replace", "path": "/first_name", "value": "Anna"}, {"op": "test", "path": "/email", "value": "other@example.com"}]`,
+			wantCode: errors.CodePatchTestFailed,
+		},
+		{
+			name:     "remove twice",
+			patch:    `[{"op": "remove", "path": "/last_name"}, {"op": "remove", "path": "/last_name"}]`,
+			wantCode: errors.CodeInvalidPatch,
+		},
+		{
+			name:     "read only path",
+			patch:    `[{"op": "replace", "path": "/status", "value": "locked"}]`,
+			wantCode: errors.CodeInvalidPatch,
+		},
+		{
+			name:     "path without slash",
+			patch:    `[{"op": "replace", "path": "first_name", "value": "Anna"}]`,
+			wantCode: errors.CodeInvalidPatch,
+		},
+		{
+			name:     "missing value",
+			patch:    `[{"op": "add", "path": "/first_name"}]`,
+			wantCode: errors.CodeInvalidPatch,
+		},
+		{
+			name:     "unsupported operation",
+			patch:    `[{"op": "move", "from": "/first_name", "path": "/last_name"}]`,
+			wantCode: errors.CodeInvalidPatch,
+		},
+		{
+			name:     "wrong type",
+			patch:    `[{"op": "replace", "path": "/email", "value": 42}]`,
+			wantCode: errors.CodeInvalidPatch,
+		},
+		{
+			name:     "not an array",
+			patch:    `{"op": "replace", "path": "/first_name", "value": "Anna"}`,
+			wantCode: errors.CodeInvalidPatch,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user := newPatchTestUser()
+			err := JSONPatch(test.patch).Apply(&user)
+			if test.wantCode != "" {
+				if err == nil || err.Code != test.wantCode {
+					t.Fatalf("Apply() error = %v, want %s", err, test.wantCode)
+				}
+				if user != newPatchTestUser() {
+					t.Errorf("user = %+v, want it unchanged after an error", user)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v, want nil", err)
+			}
+			if user != test.want {
+				t.Errorf("user = %+v, want %+v", user, test.want)
+			}
+		})
+	}
+}
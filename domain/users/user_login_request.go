@@ -1,7 +1,28 @@
 package users
 
+import (
+	"strings"
+
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+)
+
 // LoginRequest is a struct to store user email and password for oauth api
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
+
+// Validate method is to check the email and password are given,
+// the password policy is not checked here so we never tell which passwords can exist
+func (request *LoginRequest) Validate() *errors.RestErr {
+	request.Email = strings.TrimSpace(strings.ToLower(request.Email))
+
+	v := &validator{}
+	if request.Email == "" {
+		v.add("email", CauseRequired, "email is required")
+	}
+	if request.Password == "" {
+		v.add("password", CauseRequired, "password is required")
+	}
+	return v.err()
+}
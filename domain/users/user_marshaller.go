@@ -20,7 +20,9 @@ type PrivateUser struct {
 	LastName    string `json:"last_name"`
 	Email       string `json:"email"`
 	DateCreated string `json:"date_created"`
+	DateUpdated string `json:"date_updated"`
 	Status      string `json:"status"`
+	Version     int64  `json:"version"`
 }
 
 // Marshal is used to decide what user information should be returned based on different type of request
@@ -0,0 +1,125 @@
+package users
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+)
+
+// MergePatchContentType is the content type of a JSON Merge Patch request (RFC 7396)
+const MergePatchContentType = "application/merge-patch+json"
+
+// mutableFields are the JSON fields of a user that a client is allowed to change with a patch,
+// every other field (id, status, version, password...) has its own endpoint or is managed by us
+var mutableFields = map[string]bool{
+	"first_name": true,
+	"last_name":  true,
+	"email":      true,
+}
+
+// Patch is a change requested by a client that can be applied on a user
+type Patch interface {
+	Apply(user *User) *errors.RestErr
+}
+
+// NewPatch returns the patch for the body of a request with the given content type,
+// false means the content type is not one of the patch formats we support
+func NewPatch(contentType string, body []byte) (Patch, bool) {
+	switch contentType {
+	case MergePatchContentType:
+		return MergePatch(body), true
+	case JSONPatchContentType:
+		return JSONPatch(body), true
+	}
+	return nil, false
+}
+
+// IsPatchContentType returns true if the content type is one of the patch formats we support
+func IsPatchContentType(contentType string) bool {
+	_, ok := NewPatch(contentType, nil)
+	return ok
+}
+
+// MergePatch is a JSON Merge Patch document:
+// an absent field is left unchanged, a null field is cleared and any other value replaces the current one
+type MergePatch json.RawMessage
+
+// Apply method is used to apply the merge patch on the user,
+// the user is only changed if the whole patch can be applied
+func (patch MergePatch) Apply(user *User) *errors.RestErr {
+	var changes map[string]interface{}
+	if err := json.Unmarshal(patch, &changes); err != nil || changes == nil {
+		return errors.NewBadRequestError(errors.CodeInvalidPatch, "merge patch should be a json object").WithMessageKey("INVALID_PATCH.merge_not_object")
+	}
+	v := &validator{}
+	for field := range changes {
+		if !mutableFields[field] {
+			v.add(field, CauseReadOnly, fmt.Sprintf("field %s cannot be changed", field))
+		}
+	}
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	document, err := toDocument(user)
+	if err != nil {
+		return err
+	}
+	return fromDocument(mergePatch(document, changes).(map[string]interface{}), user)
+}
+
+// mergePatch is the MergePatch algorithm from RFC 7396 section 2
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = mergePatch(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
+
+// toDocument returns the user as a generic JSON document, so patches work on any field without hard-coding them
+func toDocument(user *User) (map[string]interface{}, *errors.RestErr) {
+	userJSON, err := json.Marshal(user)
+	if err != nil {
+		return nil, errors.NewInternalServerError(errors.CodeInternalError, "error when trying to patch user").WithMessageKey("INTERNAL_ERROR.patch").
+			Wrap("error when trying to marshal user", err)
+	}
+	// UseNumber keeps big ids and versions exact instead of turning them into float64
+	decoder := json.NewDecoder(bytes.NewReader(userJSON))
+	decoder.UseNumber()
+	var document map[string]interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, errors.NewInternalServerError(errors.CodeInternalError, "error when trying to patch user").WithMessageKey("INTERNAL_ERROR.patch").
+			Wrap("error when trying to decode user document", err)
+	}
+	return document, nil
+}
+
+// fromDocument replaces the user with the patched document, fields missing in the document become empty
+func fromDocument(document map[string]interface{}, user *User) *errors.RestErr {
+	documentJSON, err := json.Marshal(document)
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeInternalError, "error when trying to patch user").WithMessageKey("INTERNAL_ERROR.patch").
+			Wrap("error when trying to marshal patched document", err)
+	}
+	var patched User
+	if err := json.Unmarshal(documentJSON, &patched); err != nil {
+		return errors.NewBadRequestError(errors.CodeInvalidPatch, "invalid value in patch").WithMessageKey("INVALID_PATCH.value_type").
+			Wrap("error when trying to unmarshal patched document", err)
+	}
+	*user = patched
+	return nil
+}
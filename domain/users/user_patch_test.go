@@ -0,0 +1,110 @@
+package users
+
+import (
+	"testing"
+
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+)
+
+func newPatchTestUser() User {
+	return User{
+		ID:        1,
+		FirstName: "Ann",
+		LastName:  "Zhao",
+		Email:     "ann@example.com",
+		Status:    StatusActive,
+		Version:   2,
+	}
+}
+
+func TestMergePatchApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		want     User
+		wantCode string
+	}{
+		{
+			name:  "absent fields are unchanged",
+			patch: `{"first_name": "Anna"}`,
+			want:  User{ID: 1, FirstName: "Anna", LastName: "Zhao", Email: "ann@example.com", Status: StatusActive, Version: 2},
+		},
+		{
+			name:  "null clears the field",
+			patch: `{"last_name": null}`,
+			want:  User{ID: 1, FirstName: "Ann", LastName: "", Email: "ann@example.com", Status: StatusActive, Version: 2},
+		},
+		{
+			name:  "empty string replaces the field",
+			patch: `{"last_name": ""}`,
+			want:  User{ID: 1, FirstName: "Ann", LastName: "", Email: "ann@example.com", Status: StatusActive, Version: 2},
+		},
+		{
+			name:  "empty patch changes nothing",
+			patch: `{}`,
+			want:  newPatchTestUser(),
+		},
+		{
+			name:     "read only field",
+			patch:    `{"first_name": "Anna", "status": "locked"}`,
+			wantCode: errors.CodeValidationFailed,
+		},
+		{
+			name:     "not an object",
+			patch:    `["first_name"]`,
+			wantCode: errors.CodeInvalidPatch,
+		},
+		{
+			name:     "null document",
+			patch:    `null`,
+			wantCode: errors.CodeInvalidPatch,
+		},
+		{
+			name:     "wrong type",
+			patch:    `{"email": 42}`,
+			wantCode: errors.CodeInvalidPatch,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user := newPatchTestUser()
+			err := MergePatch(test.patch).Apply(&user)
+			if test.wantCode != "" {
+				if err == nil || err.Code != test.wantCode {
+					t.Fatalf("Apply() error = %v, want %s", err, test.wantCode)
+				}
+				if user != newPatchTestUser() {
+					t.Errorf("user = %+v, want it unchanged after an error", user)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v, want nil", err)
+			}
+			if user != test.want {
+				t.Errorf("user = %+v, want %+v", user, test.want)
+			}
+		})
+	}
+}
+
+func TestNewPatch(t *testing.T) {
+	tests := []struct {
+		contentType string
+		wantOK      bool
+	}{
+		{MergePatchContentType, true},
+		{JSONPatchContentType, true},
+		{"application/json", false},
+		{"text/plain", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if _, ok := NewPatch(test.contentType, []byte(`{}`)); ok != test.wantOK {
+			t.Errorf("NewPatch(%q) ok = %v, want %v", test.contentType, ok, test.wantOK)
+		}
+		if ok := IsPatchContentType(test.contentType); ok != test.wantOK {
+			t.Errorf("IsPatchContentType(%q) = %v, want %v", test.contentType, ok, test.wantOK)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package users
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+)
+
+// these are all the statuses a user can be in
+const (
+	StatusPending   = "pending" // default status for newly created users, until they are activated
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusLocked    = "locked"
+	StatusDeleted   = "deleted"
+)
+
+const maxReasonLength = 255 // same as the reason column in the users_audit table
+
+// statusTransitions defines which statuses a user is allowed to move to from its current status,
+// a deleted user is kept (so it can still be searched) but can never move again
+var statusTransitions = map[string][]string{
+	StatusPending:   {StatusActive, StatusDeleted},
+	StatusActive:    {StatusSuspended, StatusLocked, StatusDeleted},
+	StatusSuspended: {StatusActive, StatusDeleted},
+	StatusLocked:    {StatusActive, StatusDeleted},
+	StatusDeleted:   {},
+}
+
+// StatusChangeRequest is the JSON body for the status endpoints like /users/:user_id/suspend
+type StatusChangeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// IsValidStatus returns true if the given status is one of the statuses defined above
+func IsValidStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+// ValidateStatus is used to reject unknown statuses, e.g. in search
+func ValidateStatus(status string) *errors.RestErr {
+	if !IsValidStatus(status) {
+		return errors.NewBadRequestError(errors.CodeInvalidStatus, fmt.Sprintf("invalid status %s", status)).WithParam("status", status)
+	}
+	return nil
+}
+
+// CanTransition returns true if a user in status "from" is allowed to move to status "to"
+func CanTransition(from string, to string) bool {
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate method is to clean the reason and check it is given when the target status needs one
+func (request *StatusChangeRequest) Validate(status string) *errors.RestErr {
+	request.Reason = strings.TrimSpace(request.Reason)
+	// suspending or locking a user always needs a reason so support can explain it later
+	if request.Reason == "" && (status == StatusSuspended || status == StatusLocked) {
+		return errors.NewBadRequestError(errors.CodeReasonRequired, "reason is required")
+	}
+	v := &validator{}
+	if utf8.RuneCountInString(request.Reason) > maxReasonLength {
+		v.add("reason", CauseTooLong, fmt.Sprintf("reason should be at most %d characters", maxReasonLength), "max", maxReasonLength)
+	}
+	return v.err()
+}
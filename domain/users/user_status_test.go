@@ -0,0 +1,85 @@
+package users
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{StatusActive, StatusSuspended, true},
+		{StatusActive, StatusLocked, true},
+		{StatusActive, StatusDeleted, true},
+		{StatusSuspended, StatusActive, true},
+		{StatusLocked, StatusActive, true},
+		{StatusSuspended, StatusDeleted, true},
+		{StatusActive, StatusActive, false},
+		{StatusSuspended, StatusLocked, false},
+		{StatusLocked, StatusSuspended, false},
+		{StatusDeleted, StatusActive, false},
+		{StatusPending, StatusActive, true},
+		{StatusPending, StatusDeleted, true},
+		{StatusPending, StatusSuspended, false},
+		{StatusActive, StatusPending, false},
+		{StatusDeleted, StatusDeleted, false},
+		{"", StatusActive, false},
+	}
+	for _, test := range tests {
+		if got := CanTransition(test.from, test.to); got != test.want {
+			t.Errorf("CanTransition(%q, %q) = %v, want %v", test.from, test.to, got, test.want)
+		}
+	}
+}
+
+func TestValidateStatus(t *testing.T) {
+	for _, status := range []string{StatusPending, StatusActive, StatusSuspended, StatusLocked, StatusDeleted} {
+		if err := ValidateStatus(status); err != nil {
+			t.Errorf("ValidateStatus(%q) = %v, want nil", status, err)
+		}
+	}
+	for _, status := range []string{"", "ACTIVE", "unknown"} {
+		err := ValidateStatus(status)
+		if err == nil || err.Code != errors.CodeInvalidStatus || err.Status != http.StatusBadRequest {
+			t.Errorf("ValidateStatus(%q) = %v, want %s", status, err, errors.CodeInvalidStatus)
+		}
+	}
+}
+
+func TestStatusChangeRequestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		reason     string
+		status     string
+		wantErr    bool
+		wantReason string
+	}{
+		{"suspend with reason", " fraud ", StatusSuspended, false, "fraud"},
+		{"suspend without reason", "  ", StatusSuspended, true, ""},
+		{"lock without reason", "", StatusLocked, true, ""},
+		{"reactivate without reason", "", StatusActive, false, ""},
+		{"longest reason", strings.Repeat("é", 255), StatusLocked, false, strings.Repeat("é", 255)},
+		{"reason too long", strings.Repeat("a", 256), StatusLocked, true, strings.Repeat("a", 256)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := StatusChangeRequest{Reason: test.reason}
+			err := request.Validate(test.status)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Validate() = %v, want error %v", err, test.wantErr)
+			}
+			if err != nil && err.Code != errors.CodeReasonRequired && err.Code != errors.CodeValidationFailed {
+				t.Errorf("code = %s, want %s or %s", err.Code, errors.CodeReasonRequired, errors.CodeValidationFailed)
+			}
+			if request.Reason != test.wantReason {
+				t.Errorf("reason = %q, want %q", request.Reason, test.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package users
+
+import (
+	"fmt"
+	"net/mail"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+)
+
+// these are the codes of the causes in a validation error
+const (
+	CauseRequired      = "required"
+	CauseInvalidFormat = "invalid_format"
+	CauseTooShort      = "too_short"
+	CauseTooLong       = "too_long"
+	CauseWeakPassword  = "weak_password"
+	CauseUnknownField  = "unknown_field"
+	CauseInvalidType   = "invalid_type"
+	CauseReadOnly      = "read_only"
+)
+
+const (
+	maxNameLength     = 45 // same as the columns in the users table
+	maxEmailLength    = 45
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+// validator collects every problem of a request, so the client can fix all of them at once
+type validator struct {
+	causes []errors.Cause
+}
+
+// add is used to report a problem, params are used in the translated message next to the field name
+func (v *validator) add(field string, code string, message string, params ...interface{}) {
+	cause := errors.Cause{
+		Field:   field,
+		Code:    code,
+		Message: message,
+		Params:  map[string]string{"field": field},
+	}
+	for index := 0; index+1 < len(params); index += 2 {
+		cause.Params[fmt.Sprint(params[index])] = fmt.Sprint(params[index+1])
+	}
+	v.causes = append(v.causes, cause)
+}
+
+// err returns a validation error with all the causes, or nil if the request is valid
+func (v *validator) err() *errors.RestErr {
+	if len(v.causes) == 0 {
+		return nil
+	}
+	return errors.NewValidationError(v.causes)
+}
+
+func (v *validator) checkName(field string, name string) {
+	if utf8.RuneCountInString(name) > maxNameLength {
+		v.add(field, CauseTooLong, fmt.Sprintf("%s should be at most %d characters", field, maxNameLength), "max", maxNameLength)
+	}
+}
+
+func (v *validator) checkEmail(email string) {
+	if email == "" {
+		v.add("email", CauseRequired, "email is required")
+		return
+	}
+	if len(email) > maxEmailLength {
+		v.add("email", CauseTooLong, fmt.Sprintf("email should be at most %d characters", maxEmailLength), "max", maxEmailLength)
+		return
+	}
+	// ParseAddress also accepts "Name <email>", we only want the address itself
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		v.add("email", CauseInvalidFormat, "invalid email address")
+	}
+}
+
+// checkPassword is the password policy: 8 to 72 characters with at least one letter and one digit
+func (v *validator) checkPassword(password string) {
+	if password == "" {
+		v.add("password", CauseRequired, "password is required")
+		return
+	}
+	length := utf8.RuneCountInString(password)
+	if length < minPasswordLength {
+		v.add("password", CauseTooShort, fmt.Sprintf("password should be at least %d characters", minPasswordLength), "min", minPasswordLength)
+		return
+	}
+	if length > maxPasswordLength {
+		v.add("password", CauseTooLong, fmt.Sprintf("password should be at most %d characters", maxPasswordLength), "max", maxPasswordLength)
+		return
+	}
+
+	var hasLetter, hasDigit bool
+	for _, char := range password {
+		hasLetter = hasLetter || unicode.IsLetter(char)
+		hasDigit = hasDigit || unicode.IsDigit(char)
+	}
+	if !hasLetter || !hasDigit {
+		v.add("password", CauseWeakPassword, "password should contain at least one letter and one digit")
+	}
+}
@@ -0,0 +1,106 @@
+package users
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+)
+
+// causeCodes returns the field and code of every cause, e.g. "email:required"
+func causeCodes(err *errors.RestErr) []string {
+	if err == nil {
+		return nil
+	}
+	codes := make([]string, 0, len(err.Causes))
+	for _, cause := range err.Causes {
+		codes = append(codes, cause.Field+":"+cause.Code)
+	}
+	return codes
+}
+
+func TestUserValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		user User
+		want []string
+	}{
+		{
+			name: "valid",
+			user: User{FirstName: "Ann", LastName: "Zhao", Email: " Ann@Example.com ", Password: "secret123"},
+			want: nil,
+		},
+		{
+			name: "every field invalid",
+			user: User{FirstName: strings.Repeat("a", 46), LastName: strings.Repeat("b", 46), Email: "", Password: ""},
+			want: []string{"first_name:too_long", "last_name:too_long", "email:required", "password:required"},
+		},
+		{
+			name: "invalid email and weak password",
+			user: User{Email: "Ann <ann@example.com>", Password: "password"},
+			want: []string{"email:invalid_format", "password:weak_password"},
+		},
+		{
+			name: "email too long and password too short",
+			user: User{Email: strings.Repeat("a", 40) + "@example.com", Password: "a1"},
+			want: []string{"email:too_long", "password:too_short"},
+		},
+		{
+			name: "password too long",
+			user: User{Email: "ann@example.com", Password: strings.Repeat("a1", 37)},
+			want: []string{"password:too_long"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.user.Validate()
+			if test.want == nil {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Code != errors.CodeValidationFailed {
+				t.Fatalf("Validate() = %v, want %s", err, errors.CodeValidationFailed)
+			}
+			if got := causeCodes(err); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("causes = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUserValidateTrims(t *testing.T) {
+	user := User{FirstName: " Ann ", LastName: " Zhao ", Email: " Ann@Example.com ", Password: " secret123 "}
+	if err := user.Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want nil", err)
+	}
+	want := User{FirstName: "Ann", LastName: "Zhao", Email: "ann@example.com", Password: "secret123"}
+	if user != want {
+		t.Errorf("user = %+v, want %+v", user, want)
+	}
+}
+
+func TestValidatorParams(t *testing.T) {
+	v := &validator{}
+	v.checkName("first_name", strings.Repeat("a", 46))
+	err := v.err()
+	if err == nil || len(err.Causes) != 1 {
+		t.Fatalf("err() = %v, want one cause", err)
+	}
+	want := map[string]string{"field": "first_name", "max": "45"}
+	if !reflect.DeepEqual(err.Causes[0].Params, want) {
+		t.Errorf("params = %v, want %v", err.Causes[0].Params, want)
+	}
+	if err := (&validator{}).err(); err != nil {
+		t.Errorf("err() without causes = %v, want nil", err)
+	}
+}
+
+func TestLoginRequestValidate(t *testing.T) {
+	request := LoginRequest{Email: "  "}
+	if got, want := causeCodes(request.Validate()), []string{"email:required", "password:required"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("causes = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,52 @@
+package users
+
+import "github.com/annazhao/bookstore_users_api/utils/dates"
+
+// Version struct is one revision of a user row, a new one is stored every time the user changes
+type Version struct {
+	Version      int64  `json:"version"`
+	DateRecorded string `json:"date_recorded"`
+	User         User   `json:"user"`
+}
+
+// Versions is the type of a slice of Version
+type Versions []Version
+
+// NewVersion is used to create the revision for the current state of the user,
+// it is recorded at the time the user was last updated
+func NewVersion(user User) *Version {
+	dateRecorded := user.DateUpdated
+	if dateRecorded == "" {
+		dateRecorded = dates.GetNowDBFormat()
+	}
+	return &Version{
+		DateRecorded: dateRecorded,
+		User:         user,
+	}
+}
+
+// versionView is how a revision is presented to the client,
+// the user inside goes through the same public/private shaping as User.Marshal
+type versionView struct {
+	Version      int64       `json:"version"`
+	DateRecorded string      `json:"date_recorded"`
+	User         interface{} `json:"user"`
+}
+
+// Marshal is used to decide what revision information should be returned based on different type of request
+func (version *Version) Marshal(isPublic bool) interface{} {
+	return versionView{
+		Version:      version.Version,
+		DateRecorded: version.DateRecorded,
+		User:         version.User.Marshal(isPublic),
+	}
+}
+
+// Marshal is used to returen a slice of revisions
+func (versions Versions) Marshal(isPublic bool) []interface{} {
+	result := make([]interface{}, len(versions))
+	for index, version := range versions {
+		result[index] = version.Marshal(isPublic)
+	}
+	return result
+}
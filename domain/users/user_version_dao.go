@@ -0,0 +1,102 @@
+package users
+
+import (
+	"context"
+	"fmt"
+
+	usersdb "github.com/annazhao/bookstore_users_api/datasources/mysql/users_db"
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"github.com/annazhao/bookstore_users_api/utils/mysqls"
+)
+
+const (
+	queryInsertVersion   = "INSERT INTO users_versions(user_id, version, first_name, last_name, email, date_created, status, date_recorded) VALUES(?, ?, ?, ?, ?, ?, ?, ?);"
+	queryFindVersions    = "SELECT version, date_recorded, user_id, first_name, last_name, email, date_created, status FROM users_versions WHERE user_id=? ORDER BY version;"
+	queryFindVersionAsOf = "SELECT version, date_recorded, user_id, first_name, last_name, email, date_created, status FROM users_versions WHERE user_id=? AND date_recorded<=? ORDER BY version DESC LIMIT 1;"
+)
+
+// Save method is used to store the revision, pass the same transaction used for the change itself,
+// the revision number is the version of the user row so both always agree
+func (version *Version) Save(ctx context.Context, exec usersdb.Executor) *errors.RestErr {
+	ctx, done := usersdb.TrackQuery(ctx, "insert_user_version")
+	defer done()
+	stmt, err := exec.PrepareContext(ctx, queryInsertVersion)
+	if err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare save user version statement", err)
+	}
+	defer stmt.Close()
+
+	user := version.User
+	version.Version = user.Version
+	if _, err = stmt.ExecContext(ctx, user.ID, version.Version, user.FirstName, user.LastName, user.Email, user.DateCreated, user.Status, version.DateRecorded); err != nil {
+		return errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to save user version", err)
+	}
+	return nil
+}
+
+// FindVersions method is used to get all the revisions of the user, oldest first
+func (user *User) FindVersions(ctx context.Context) (Versions, *errors.RestErr) {
+	ctx, done := usersdb.TrackQuery(ctx, "find_user_versions")
+	defer done()
+	stmt, err := usersdb.Client.PrepareContext(ctx, queryFindVersions)
+	if err != nil {
+		return nil, errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare find user versions statement", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, user.ID)
+	if err != nil {
+		return nil, errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to find user versions", err)
+	}
+	defer rows.Close()
+
+	results := make(Versions, 0)
+	for rows.Next() {
+		var version Version
+		if err := scanVersion(rows, &version); err != nil {
+			return nil, errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to scan user version row into version struct", err)
+		}
+		results = append(results, version)
+	}
+
+	if len(results) == 0 {
+		return nil, errors.NewNotFoundError(errors.CodeUserVersionNotFound, fmt.Sprintf("no versions found for user %d", user.ID)).WithParam("user_id", user.ID)
+	}
+	return results, nil
+}
+
+// FindVersionAsOf method is used to get the revision of the user that was current at the given datetime (in db format)
+func (user *User) FindVersionAsOf(ctx context.Context, asOf string) (*Version, *errors.RestErr) {
+	ctx, done := usersdb.TrackQuery(ctx, "find_user_version_as_of")
+	defer done()
+	stmt, err := usersdb.Client.PrepareContext(ctx, queryFindVersionAsOf)
+	if err != nil {
+		return nil, errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to prepare find user version as of statement", err)
+	}
+	defer stmt.Close()
+
+	var version Version
+	if err := scanVersion(stmt.QueryRowContext(ctx, user.ID, asOf), &version); err != nil {
+		if mysqls.IsNoRows(err) {
+			return nil, errors.NewNotFoundError(errors.CodeUserVersionNotFound, fmt.Sprintf("no version of user %d as of %s", user.ID, asOf)).
+				WithMessageKey("USER_VERSION_NOT_FOUND.as_of").WithParam("user_id", user.ID).WithParam("as_of", asOf)
+		}
+		return nil, errors.NewInternalServerError(errors.CodeDatabaseError, "database error").Wrap("error when trying to find user version as of", err)
+	}
+	return &version, nil
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVersion(row scanner, version *Version) error {
+	if err := row.Scan(&version.Version, &version.DateRecorded,
+		&version.User.ID, &version.User.FirstName, &version.User.LastName, &version.User.Email, &version.User.DateCreated, &version.User.Status); err != nil {
+		return err
+	}
+	version.User.Version = version.Version
+	version.User.DateUpdated = version.DateRecorded
+	return nil
+}
@@ -0,0 +1,114 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// CheckFunc tells if a dependency can be used, it should give up when ctx is done
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of every check, Status is only up if every check is up
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+var (
+	mutex  sync.RWMutex
+	checks = make([]check, 0)
+
+	draining    atomic.Bool
+	errDraining = errors.New("application is shutting down")
+)
+
+// Register is used by each dependency (database, cache, message broker...) to add the check readiness depends on
+func Register(name string, fn CheckFunc) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	checks = append(checks, check{name: name, fn: fn})
+}
+
+// SetDraining is used on shutdown, so the load balancer stops sending new requests while in-flight ones finish
+func SetDraining() {
+	draining.Store(true)
+}
+
+// IsDraining tells if the application is shutting down
+func IsDraining() bool {
+	return draining.Load()
+}
+
+// Run is used to run every registered check at the same time, each one has timeout to answer
+func Run(ctx context.Context, timeout time.Duration) Report {
+	mutex.RLock()
+	registered := make([]check, len(checks))
+	copy(registered, checks)
+	mutex.RUnlock()
+
+	results := make([]Result, len(registered))
+	var wg sync.WaitGroup
+	for i, c := range registered {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, timeout, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: results}
+	for _, result := range results {
+		if result.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, timeout time.Duration, c check) Result {
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(checkCtx)
+	result := Result{
+		Name:      c.name,
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// CheckNotDraining is the check which makes the application not ready once it is shutting down
+func CheckNotDraining(ctx context.Context) error {
+	if IsDraining() {
+		return errDraining
+	}
+	return nil
+}
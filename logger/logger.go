@@ -1,44 +1,118 @@
 package logger
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/annazhao/bookstore_users_api/config"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-var log *zap.Logger
+var (
+	log *zap.Logger
+
+	// level is shared by every logger built by Init, so it can be changed at runtime with SetLevel
+	level = zap.NewAtomicLevel()
+)
 
+// the default logger (info level, json to standard output) is used until Init is called with the configuration
 func init() {
-	logConfig := zap.Config{
-		OutputPaths: []string{"stdout"}, // now we are just logging to standard output
-		Level:       zap.NewAtomicLevelAt(zap.InfoLevel),
-		Encoding:    "json",
-		EncoderConfig: zapcore.EncoderConfig{
-			LevelKey:     "level",
-			TimeKey:      "time",
-			MessageKey:   "msg",
-			EncodeTime:   zapcore.ISO8601TimeEncoder,
-			EncodeLevel:  zapcore.LowercaseLevelEncoder,
-			EncodeCaller: zapcore.ShortCallerEncoder,
-		},
+	defaults := config.Default()
+	if err := Init(defaults.Log); err != nil {
+		panic(err)
 	}
+}
 
-	var err error
-	if log, err = logConfig.Build(); err != nil {
-		panic(err)
+// Init is used to build the logger with the level, encoding and outputs of the configuration,
+// stdout and stderr are used as they are, any other output is a file rotated as set in cfg.File
+func Init(cfg config.LogConfig) error {
+	if err := SetLevel(cfg.Level); err != nil {
+		return err
+	}
+
+	encoderConfig := zapcore.EncoderConfig{
+		LevelKey:     "level",
+		TimeKey:      "time",
+		MessageKey:   "msg",
+		EncodeTime:   zapcore.ISO8601TimeEncoder,
+		EncodeLevel:  zapcore.LowercaseLevelEncoder,
+		EncodeCaller: zapcore.ShortCallerEncoder,
+		// durations like timeouts and backoffs are written as 20s instead of nanoseconds
+		EncodeDuration: zapcore.StringDurationEncoder,
+	}
+	encoder := zapcore.NewJSONEncoder(encoderConfig)
+	if cfg.Encoding == config.LogEncodingConsole {
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	}
+
+	outputs := make([]zapcore.WriteSyncer, 0, len(cfg.Outputs))
+	for _, output := range cfg.Outputs {
+		switch output {
+		case "stdout":
+			outputs = append(outputs, zapcore.Lock(os.Stdout))
+		case "stderr":
+			outputs = append(outputs, zapcore.Lock(os.Stderr))
+		default:
+			outputs = append(outputs, zapcore.AddSync(&lumberjack.Logger{
+				Filename:   output,
+				MaxSize:    cfg.File.MaxSizeMB,
+				MaxBackups: cfg.File.MaxBackups,
+				MaxAge:     cfg.File.MaxAgeDays,
+				Compress:   cfg.File.Compress,
+			}))
+		}
+	}
+
+	log = zap.New(zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(outputs...), level))
+	return nil
+}
+
+// SetLevel is used to change the level of the logger while the application is running, e.g. to debug an issue,
+// it accepts debug, info, warn and error
+func SetLevel(newLevel string) error {
+	parsed, err := zapcore.ParseLevel(newLevel)
+	if err != nil {
+		return err
 	}
+	if parsed > zapcore.ErrorLevel {
+		return fmt.Errorf("unsupported log level %q", newLevel)
+	}
+	level.SetLevel(parsed)
+	return nil
+}
+
+// GetLevel returns the current level of the logger, e.g. info
+func GetLevel() string {
+	return level.String()
+}
+
+// Debug method is overwritten here
+func Debug(msg string, tags ...zap.Field) {
+	log.Debug(msg, tags...)
 }
 
 // Info method is overwritten here
 func Info(msg string, tags ...zap.Field) {
 	log.Info(msg, tags...)
-	log.Sync()
+}
+
+// Warn method is overwritten here
+func Warn(msg string, tags ...zap.Field) {
+	log.Warn(msg, tags...)
 }
 
 // Error method is overwritten here
 func Error(msg string, err error, tags ...zap.Field) {
 	tags = append(tags, zap.NamedError("error", err))
 	log.Error(msg, tags...)
-	log.Sync()
+}
+
+// Sync is used on shutdown to flush the buffered logs,
+// the messages are not synced one by one so logging does not slow down the requests
+func Sync() error {
+	return log.Sync()
 }
 
 func GetLogger() *zap.Logger {
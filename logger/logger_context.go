@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying fields (e.g. the request id), every line logged with it gets them,
+// the fields already in ctx are kept
+func NewContext(ctx context.Context, fields ...zap.Field) context.Context {
+	existing, _ := ctx.Value(contextKey{}).([]zap.Field)
+	all := make([]zap.Field, 0, len(existing)+len(fields))
+	all = append(all, existing...)
+	all = append(all, fields...)
+	return context.WithValue(ctx, contextKey{}, all)
+}
+
+// DebugContext is the same as Debug, with the fields of ctx and its trace id
+func DebugContext(ctx context.Context, msg string, tags ...zap.Field) {
+	Debug(msg, append(contextFields(ctx), tags...)...)
+}
+
+// InfoContext is the same as Info, with the fields of ctx and its trace id
+func InfoContext(ctx context.Context, msg string, tags ...zap.Field) {
+	Info(msg, append(contextFields(ctx), tags...)...)
+}
+
+// WarnContext is the same as Warn, with the fields of ctx and its trace id
+func WarnContext(ctx context.Context, msg string, tags ...zap.Field) {
+	Warn(msg, append(contextFields(ctx), tags...)...)
+}
+
+// ErrorContext is the same as Error, with the fields of ctx and its trace id
+func ErrorContext(ctx context.Context, msg string, err error, tags ...zap.Field) {
+	Error(msg, err, append(contextFields(ctx), tags...)...)
+}
+
+// contextFields returns the fields of ctx, plus the trace and span ids so log lines can be found from a trace
+func contextFields(ctx context.Context) []zap.Field {
+	existing, _ := ctx.Value(contextKey{}).([]zap.Field)
+	fields := make([]zap.Field, 0, len(existing)+2)
+	fields = append(fields, existing...)
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		fields = append(fields,
+			zap.String("trace_id", spanContext.TraceID().String()),
+			zap.String("span_id", spanContext.SpanID().String()))
+	}
+	return fields
+}
@@ -1,7 +1,22 @@
 package main
 
-import "github.com/annazhao/bookstore_users_api/app"
+import (
+	"fmt"
+	"os"
+
+	"github.com/annazhao/bookstore_users_api/app"
+	"github.com/annazhao/bookstore_users_api/config"
+)
 
 func main() {
-	app.StartApplication()
+	// the configuration comes from the config file, environment variables and flags, see config.Load
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := app.StartApplication(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
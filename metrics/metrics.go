@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// every metric of the users api starts with this namespace, e.g. users_api_http_requests_total
+const namespace = "users_api"
+
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of http requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of http requests by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	loginAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_attempts_total",
+		Help:      "Number of login attempts by result and reason of the failure.",
+	}, []string{"result", "reason"})
+
+	usersCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "users_created_total",
+		Help:      "Number of users created.",
+	})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of database queries by query name.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"query"})
+)
+
+// ObserveRequest is used once the response is sent, route is the template (e.g. /users/:user_id) so ids do not explode the labels
+func ObserveRequest(method string, route string, status string, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, status).Inc()
+	httpRequestDuration.WithLabelValues(method, route, status).Observe(duration.Seconds())
+}
+
+// ObserveLogin is used for every login attempt, reason is the error code of a failure and empty on success
+func ObserveLogin(result string, reason string) {
+	loginAttempts.WithLabelValues(result, reason).Inc()
+}
+
+// ObserveUserCreated is used each time a user is created
+func ObserveUserCreated() {
+	usersCreated.Inc()
+}
+
+// ObserveQuery records the latency of one database query, DAO methods should not call it directly,
+// they use usersdb.TrackQuery which calls it and also traces the query
+func ObserveQuery(query string, start time.Time) {
+	dbQueryDuration.WithLabelValues(query).Observe(time.Since(start).Seconds())
+}
+
+// RegisterDatabase is used once the connection pool is created to expose its stats (open, in use, idle, waits...)
+func RegisterDatabase(name string, db *sql.DB) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
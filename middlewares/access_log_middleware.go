@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/annazhao/bookstore_users_api/config"
+	"github.com/annazhao/bookstore_users_api/domain/audits"
+	"github.com/annazhao/bookstore_users_api/logger"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AccessLog is used to write one JSON line for each request, with the request id, trace id and caller id
+// of the request context, and the authenticated user if there is one. Requests to the excluded paths
+// (health checks...) are not logged, and only the sample ratio of the successful ones are, so the failures are never lost
+func AccessLog(cfg config.AccessLog) gin.HandlerFunc {
+	excluded := make(map[string]bool, len(cfg.ExcludePaths))
+	for _, path := range cfg.ExcludePaths {
+		excluded[path] = true
+	}
+
+	return func(c *gin.Context) {
+		if excluded[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		if status < http.StatusBadRequest && rand.Float64() >= cfg.SampleRatio {
+			return
+		}
+
+		// gin reports -1 when nothing was written
+		bytes := c.Writer.Size()
+		if bytes < 0 {
+			bytes = 0
+		}
+		// c.Request has the context set by the next middlewares, so the trace id is there too,
+		// the request id, route and caller id come from the context set by RequestID
+		fields := []zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.Int("status", status),
+			zap.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			zap.Int("bytes", bytes),
+			zap.String("client_ip", c.ClientIP()),
+			zap.String("user_agent", c.Request.UserAgent()),
+		}
+		// the authenticated user is set by an auth middleware like AdminAuth, most requests have none
+		if user := c.GetString(audits.AuthenticatedActorKey); user != "" {
+			fields = append(fields, zap.String("user", user))
+		}
+		logger.InfoContext(c.Request.Context(), "request", fields...)
+	}
+}
@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/annazhao/bookstore_users_api/domain/audits"
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"github.com/annazhao/bookstore_users_api/utils/responses"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth is used to protect the /admin endpoints, the request needs Authorization: Bearer <token>,
+// the caller is then the verified "admin" actor of the audit trail
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		// the constant time comparison does not tell how much of the token was right
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			responses.AbortWithError(c, errors.NewUnauthorizedError(errors.CodeUnauthorized, "a valid admin token is required"))
+			return
+		}
+		c.Set(audits.AuthenticatedActorKey, "admin")
+		c.Next()
+	}
+}
@@ -0,0 +1,113 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/annazhao/bookstore_users_api/services"
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"github.com/annazhao/bookstore_users_api/utils/responses"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	headerIdempotencyKey = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+)
+
+// perRequestHeaders belong to the request which produced the stored response, so they are not replayed:
+// the retry has its own request id and trace, and the server sets its own date and length
+var perRequestHeaders = map[string]bool{
+	"X-Request-Id":   true,
+	"Date":           true,
+	"Traceparent":    true,
+	"Tracestate":     true,
+	"Content-Length": true,
+}
+
+// responseRecorder keeps a copy of everything written to the client
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// Idempotency makes an unsafe endpoint safe to retry:
+// the first request with an Idempotency-Key header is handled and its response stored,
+// a retry with the same key and body gets the stored response back instead of being handled again
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(headerIdempotencyKey)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			restErr := errors.NewBadRequestError(errors.CodeInvalidIdempotencyKey, "idempotency key is too long")
+			responses.AbortWithError(c, restErr)
+			return
+		}
+
+		body, err := c.GetRawData()
+		if err != nil {
+			restErr := errors.NewBadRequestError(errors.CodeInvalidJSON, "invalid request body").WithMessageKey("INVALID_JSON.request_body")
+			responses.AbortWithError(c, restErr)
+			return
+		}
+		// the handler still needs to read the body
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := c.Request.Method + " " + c.Request.URL.Path
+		record, replay, restErr := services.IdempotencyService.BeginRequest(c.Request.Context(), key, scope, c.Request.Header, body)
+		if restErr != nil {
+			responses.AbortWithError(c, restErr)
+			return
+		}
+		if replay {
+			for name, values := range record.ResponseHeaders {
+				if perRequestHeaders[http.CanonicalHeaderKey(name)] {
+					continue
+				}
+				// the stored values replace what the middlewares already set, so no header is sent twice
+				c.Writer.Header()[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+			}
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(record.ResponseStatus, record.ResponseHeaders.Get("Content-Type"), record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		// if the handler panics, the key is released on the way up to the recovery middleware,
+		// so the client can retry instead of getting 409 until the key expires
+		// the queries now stop when the client goes away, but the key must still be finished or released then
+		finishCtx := context.WithoutCancel(c.Request.Context())
+		finished := false
+		defer func() {
+			if !finished {
+				services.IdempotencyService.FinishRequest(finishCtx, record, http.StatusInternalServerError, nil, nil)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = recorder
+		c.Next()
+
+		status := c.Writer.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		services.IdempotencyService.FinishRequest(finishCtx, record, status, c.Writer.Header().Clone(), recorder.body.Bytes())
+		finished = true
+	}
+}
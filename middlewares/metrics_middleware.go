@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/annazhao/bookstore_users_api/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute is the route label of requests which do not match any route, e.g. 404s of scanners
+const unmatchedRoute = "unmatched"
+
+// Metrics is used to count every request and measure its latency by route template and status
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		metrics.ObserveRequest(c.Request.Method, route, strconv.Itoa(c.Writer.Status()), time.Since(start))
+	}
+}
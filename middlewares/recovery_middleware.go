@@ -0,0 +1,21 @@
+package middlewares
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"github.com/annazhao/bookstore_users_api/utils/responses"
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery is used instead of gin.Recovery, so the client gets our usual internal server error
+// and the panic is logged with zap like every other server error, with its stack as the cause
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		cause := fmt.Errorf("%v\n%s", recovered, debug.Stack())
+		restErr := errors.NewInternalServerError(errors.CodeInternalError, "internal server error").Wrap("panic when handling request", cause)
+		responses.AbortWithError(c, restErr)
+	})
+}
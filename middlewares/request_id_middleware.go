@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"regexp"
+
+	"github.com/annazhao/bookstore_users_api/logger"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	headerRequestID = "X-Request-Id"
+	headerCallerID  = "X-Caller-Id"
+)
+
+// validRequestID is what we accept from the caller, anything else (too long, spaces...) is replaced by a new id,
+// the id is saved with every audit entry, so the length limit is the one of users_audit.request_id in schema.sql
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestID is used to give every request an id: the X-Request-Id of the caller is kept so the id follows
+// the request across services, otherwise a new one is generated. The id is sent back in X-Request-Id,
+// and every line logged with the request context gets it with the route, caller id and user id
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(headerRequestID)
+		if !validRequestID.MatchString(requestID) {
+			requestID = rand.Text()
+		}
+		// the handlers read the id from the request header, so it is replaced when we generated one
+		c.Request.Header.Set(headerRequestID, requestID)
+		c.Header(headerRequestID, requestID)
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		fields := []zap.Field{
+			zap.String("request_id", requestID),
+			zap.String("route", route),
+		}
+		if callerID := c.GetHeader(headerCallerID); callerID != "" {
+			fields = append(fields, zap.String("caller_id", callerID))
+		}
+		if userID := c.Param("user_id"); userID != "" {
+			fields = append(fields, zap.String("user_id", userID))
+		}
+		c.Request = c.Request.WithContext(logger.NewContext(c.Request.Context(), fields...))
+		c.Next()
+	}
+}
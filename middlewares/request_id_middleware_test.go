@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantKept bool
+	}{
+		{"absent", "", false},
+		{"uuid", "3f2a4c1e-9b7d-4e2a-8c1f-0a1b2c3d4e5f", true},
+		{"64 characters", strings.Repeat("a", 64), true},
+		{"65 characters", strings.Repeat("a", 65), false},
+		{"spaces", "my request", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := gin.New()
+			var seen string
+			router.GET("/ping", RequestID(), func(c *gin.Context) {
+				seen = c.GetHeader(headerRequestID)
+				c.Status(http.StatusOK)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/ping", nil)
+			if test.header != "" {
+				request.Header.Set(headerRequestID, test.header)
+			}
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, request)
+
+			sent := response.Header().Get(headerRequestID)
+			if sent != seen || !validRequestID.MatchString(sent) {
+				t.Fatalf("handler saw %q and client got %q, want the same valid id", seen, sent)
+			}
+			if kept := sent == test.header; kept != test.wantKept {
+				t.Errorf("id = %q, want kept %v", sent, test.wantKept)
+			}
+		})
+	}
+}
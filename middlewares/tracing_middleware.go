@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/annazhao/bookstore_users_api/tracing"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing is used to continue the trace of the caller from its traceparent header (or start a new one),
+// it starts the server span of the request and a child span for the controller, e.g. users.Create
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		ctx := tracing.Extract(c.Request.Context(), c.Request.Header)
+		ctx, span := tracing.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", c.Request.URL.Path),
+				attribute.String("client.address", c.ClientIP()),
+			))
+		defer span.End()
+
+		// c.HandlerName() is the full name of the function, e.g. github.com/.../controllers/users.Create
+		ctx, controllerSpan := tracing.Start(ctx, path.Base(c.HandlerName()))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		controllerSpan.End()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
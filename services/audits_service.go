@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+
+	"github.com/annazhao/bookstore_users_api/domain/audits"
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// AuditsService is the type of auditsService, as well as the type of auditsServiceInterface
+var AuditsService auditsService
+
+type auditsService struct {
+}
+
+type auditsServiceInterface interface {
+	SearchAudits(context.Context, int64, string, int) (audits.Entries, *errors.RestErr)
+}
+
+// SearchAudits function is used to find the audit trail of a target user and/or an actor
+func (s *auditsService) SearchAudits(ctx context.Context, targetUserID int64, actor string, limit int) (audits.Entries, *errors.RestErr) {
+	if targetUserID <= 0 && actor == "" {
+		return nil, errors.NewBadRequestError(errors.CodeInvalidParameter, "user_id or actor is required").WithMessageKey("INVALID_PARAMETER.user_id_or_actor")
+	}
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+
+	var entries audits.Entries
+	if err := entries.Search(ctx, targetUserID, actor, limit); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/annazhao/bookstore_users_api/config"
+	"github.com/annazhao/bookstore_users_api/domain/idempotency"
+	"github.com/annazhao/bookstore_users_api/logger"
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"go.uber.org/zap"
+)
+
+// IdempotencyService is the type of idempotencyService, as well as the type of idempotencyServiceInterface
+var IdempotencyService idempotencyService
+
+// idempotencyTTL is how long a key and its response are kept, it comes from users.idempotency_ttl,
+// idempotencyLease is how long a request in progress keeps its key, it comes from users.idempotency_lease
+var (
+	idempotencyTTL   = config.Default().Users.IdempotencyTTL
+	idempotencyLease = config.Default().Users.IdempotencyLease
+)
+
+// Configure is used at startup to give the services their configuration
+func Configure(cfg config.UsersConfig) {
+	idempotencyTTL = cfg.IdempotencyTTL
+	idempotencyLease = cfg.IdempotencyLease
+}
+
+type idempotencyService struct {
+}
+
+type idempotencyServiceInterface interface {
+	BeginRequest(context.Context, string, string, http.Header, []byte) (*idempotency.Record, bool, *errors.RestErr)
+	FinishRequest(context.Context, *idempotency.Record, int, http.Header, []byte)
+}
+
+// BeginRequest function is used when a request with an idempotency key comes in,
+// it returns the stored record and true if the response should be replayed,
+// or the newly reserved record and false if the request should be handled now
+func (s *idempotencyService) BeginRequest(ctx context.Context, key string, scope string, headers http.Header, body []byte) (*idempotency.Record, bool, *errors.RestErr) {
+	record := &idempotency.Record{
+		Key:         key,
+		Scope:       scope,
+		Fingerprint: idempotency.GetFingerprint(scope, headers, body),
+	}
+
+	existing, err := record.Reserve(ctx, idempotencyTTL, idempotencyLease)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing == nil {
+		return record, false, nil
+	}
+
+	if existing.Fingerprint != record.Fingerprint {
+		return nil, false, errors.NewUnprocessableEntityError(errors.CodeIdempotencyKeyReused, "idempotency key was already used with a different request")
+	}
+	if !existing.Completed {
+		return nil, false, errors.NewConflictError(errors.CodeIdempotencyKeyInProgress, "a request with this idempotency key is still in progress")
+	}
+	return existing, true, nil
+}
+
+// FinishRequest function is used to store the response of a request we have just handled,
+// server errors are not stored so the client can retry with the same key
+func (s *idempotencyService) FinishRequest(ctx context.Context, record *idempotency.Record, status int, headers http.Header, body []byte) {
+	var err *errors.RestErr
+	if status >= http.StatusInternalServerError {
+		err = record.Release(ctx)
+	} else {
+		record.ResponseStatus = status
+		record.ResponseHeaders = headers
+		record.ResponseBody = body
+		err = record.Complete(ctx)
+	}
+	// the response is already sent to the client, so all we can do here is logging
+	if err != nil {
+		logger.ErrorContext(ctx, "idempotency key could not be finished", err, zap.String("key", record.Key), zap.String("scope", record.Scope))
+	}
+}
@@ -1,10 +1,20 @@
 package services
 
 import (
+	"context"
+	"fmt"
+	"time"
+
+	usersdb "github.com/annazhao/bookstore_users_api/datasources/mysql/users_db"
+	"github.com/annazhao/bookstore_users_api/domain/audits"
 	"github.com/annazhao/bookstore_users_api/domain/users"
+	"github.com/annazhao/bookstore_users_api/logger"
+	"github.com/annazhao/bookstore_users_api/metrics"
+	"github.com/annazhao/bookstore_users_api/tracing"
 	"github.com/annazhao/bookstore_users_api/utils/cryptos"
 	"github.com/annazhao/bookstore_users_api/utils/dates"
 	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"go.uber.org/zap"
 )
 
 // UsersService is the type of usersService, as well as the type of usersServiceInterface
@@ -15,46 +25,103 @@ type usersService struct {
 
 // because type usersService has all the method that usersServiceInterface has,
 // so usersService is also the type of usersServiceInterface
+// ctx carries the origin of the request (who and from where), which is written to the audit trail
 type usersServiceInterface interface {
-	CreateUser(users.User) (*users.User, *errors.RestErr)
-	GetUser(int64) (*users.User, *errors.RestErr)
-	UpdateUser(bool, user users.User) (*users.User, *errors.RestErr)
-	DeleteUser(int64) *errors.RestErr
-	SearchUser(string) (users.Users, *errors.RestErr)
-	LoginUser(users.LoginRequest) (*users.User, *errors.RestErr)
+	CreateUser(context.Context, users.User) (*users.User, *errors.RestErr)
+	GetUser(context.Context, int64) (*users.User, *errors.RestErr)
+	GetUserAsOf(context.Context, int64, time.Time) (*users.User, *errors.RestErr)
+	GetUserVersions(context.Context, int64) (users.Versions, *errors.RestErr)
+	UpdateUser(context.Context, bool, users.User) (*users.User, *errors.RestErr)
+	PatchUser(context.Context, int64, int64, users.Patch) (*users.User, *errors.RestErr)
+	DeleteUser(context.Context, int64, int64) *errors.RestErr
+	SearchUser(context.Context, string) (users.Users, *errors.RestErr)
+	ChangeUserStatus(context.Context, int64, string, string, users.StatusChangeRequest) (*users.User, *errors.RestErr)
+	LoginUser(context.Context, users.LoginRequest) (*users.User, *errors.RestErr)
 }
 
 // CreateUser function here is used to create a user record in database
 // here is where the business logic happens and defines
-func (s *usersService) CreateUser(user users.User) (*users.User, *errors.RestErr) {
+func (s *usersService) CreateUser(ctx context.Context, user users.User) (*users.User, *errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "usersService.CreateUser")
+	defer span.End()
+
 	if err := user.Validate(); err != nil {
 		return nil, err
 	}
-	user.Status = users.StatusActive // default status for new users is active
+	user.Status = users.StatusPending // new users can only log in once they are activated
 	user.DateCreated = dates.GetNowDBFormat()
+	user.DateUpdated = user.DateCreated
 	user.Password = cryptos.GetMd5(user.Password) // hashed password
 
-	if err := user.Save(); err != nil {
+	// the user and its audit entry are saved in the same transaction, so we never have one without the other
+	err := usersdb.WithTransaction(ctx, func(tx usersdb.Executor) *errors.RestErr {
+		if err := user.Save(ctx, tx); err != nil {
+			return err
+		}
+		if err := users.NewVersion(user).Save(ctx, tx); err != nil {
+			return err
+		}
+		entry := audits.NewEntry(ctx, audits.ActionCreate, user.ID, nil, user.Marshal(false))
+		return entry.Save(ctx, tx)
+	})
+	if err != nil {
 		return nil, err
 	}
+	metrics.ObserveUserCreated()
 	return &user, nil
 }
 
 // GetUser function is used to get a user from database based on user id
-func (s *usersService) GetUser(userID int64) (*users.User, *errors.RestErr) {
+func (s *usersService) GetUser(ctx context.Context, userID int64) (*users.User, *errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "usersService.GetUser")
+	defer span.End()
+
 	result := &users.User{ID: userID}
-	if err := result.Get(); err != nil {
+	if err := result.Get(ctx); err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-// UpdateUser function is used to update a user in database
-func (s *usersService) UpdateUser(isPartial bool, user users.User) (*users.User, *errors.RestErr) {
+// GetUserAsOf function is used to get the user exactly as it looked at the given time
+func (s *usersService) GetUserAsOf(ctx context.Context, userID int64, asOf time.Time) (*users.User, *errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "usersService.GetUserAsOf")
+	defer span.End()
+
+	user := &users.User{ID: userID}
+	version, err := user.FindVersionAsOf(ctx, dates.ToDBFormat(asOf))
+	if err != nil {
+		return nil, err
+	}
+	return &version.User, nil
+}
+
+// GetUserVersions function is used to get every revision of a user
+func (s *usersService) GetUserVersions(ctx context.Context, userID int64) (users.Versions, *errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "usersService.GetUserVersions")
+	defer span.End()
+
+	user := &users.User{ID: userID}
+	return user.FindVersions(ctx)
+}
+
+// UpdateUser function is used to update a user in database,
+// if user.Version is set, the update only happens when the user is still at that version
+func (s *usersService) UpdateUser(ctx context.Context, isPartial bool, user users.User) (*users.User, *errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "usersService.UpdateUser")
+	defer span.End()
+
 	current := &users.User{ID: user.ID}
-	if err := current.Get(); err != nil {
+	if err := current.Get(ctx); err != nil {
+		return nil, err
+	}
+	if err := checkVersion(current, user.Version); err != nil {
 		return nil, err
 	}
+	if err := checkNotDeleted(current); err != nil {
+		return nil, err
+	}
+	before := current.Marshal(false)
 
 	// if we only want to update partial field from JSON request, we need to use PATCH method
 	if isPartial {
@@ -72,24 +139,134 @@ func (s *usersService) UpdateUser(isPartial bool, user users.User) (*users.User,
 		current.LastName = user.LastName
 		current.Email = user.Email
 	}
+	if err := current.ValidateProfile(); err != nil {
+		return nil, err
+	}
 
-	if err := current.Update(); err != nil {
+	if err := saveUpdate(ctx, before, current); err != nil {
 		return nil, err
 	}
 	return current, nil
 }
 
-// DeleteUser function is used to delete a user in database
-func (s *usersService) DeleteUser(userID int64) *errors.RestErr {
+// PatchUser function is used to apply a patch (e.g. a JSON Merge Patch) on a user in database,
+// if expectedVersion is not 0, the patch only happens when the user is still at that version
+func (s *usersService) PatchUser(ctx context.Context, userID int64, expectedVersion int64, patch users.Patch) (*users.User, *errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "usersService.PatchUser")
+	defer span.End()
+
+	current := &users.User{ID: userID}
+	if err := current.Get(ctx); err != nil {
+		return nil, err
+	}
+	if err := checkVersion(current, expectedVersion); err != nil {
+		return nil, err
+	}
+	if err := checkNotDeleted(current); err != nil {
+		return nil, err
+	}
+	before := current.Marshal(false)
+
+	if err := patch.Apply(current); err != nil {
+		return nil, err
+	}
+	if err := current.ValidateProfile(); err != nil {
+		return nil, err
+	}
+
+	if err := saveUpdate(ctx, before, current); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// saveUpdate is shared by UpdateUser and PatchUser, it saves the user with its revision and audit entry in one transaction
+func saveUpdate(ctx context.Context, before interface{}, current *users.User) *errors.RestErr {
+	// nothing changed (e.g. an empty merge patch), so there is no new version, revision or audit entry to write
+	if before == current.Marshal(false) {
+		return nil
+	}
+	current.DateUpdated = dates.GetNowDBFormat()
+
+	return usersdb.WithTransaction(ctx, func(tx usersdb.Executor) *errors.RestErr {
+		if err := current.Update(ctx, tx); err != nil {
+			return err
+		}
+		if err := users.NewVersion(*current).Save(ctx, tx); err != nil {
+			return err
+		}
+		entry := audits.NewEntry(ctx, audits.ActionUpdate, current.ID, before, current.Marshal(false))
+		return entry.Save(ctx, tx)
+	})
+}
+
+// DeleteUser function is used to delete a user, the user is only moved to the deleted status
+// so its row and history are kept, if expectedVersion is not 0 it must still be at that version
+func (s *usersService) DeleteUser(ctx context.Context, userID int64, expectedVersion int64) *errors.RestErr {
+	ctx, span := tracing.Start(ctx, "usersService.DeleteUser")
+	defer span.End()
+
 	user := &users.User{ID: userID}
-	return user.Delete() // Delete() is a method
+	if err := user.Get(ctx); err != nil {
+		return err
+	}
+	if err := checkVersion(user, expectedVersion); err != nil {
+		return err
+	}
+	if !users.CanTransition(user.Status, users.StatusDeleted) {
+		return newStatusTransitionDeniedError(user.Status, users.StatusDeleted)
+	}
+
+	before := user.Marshal(false)
+	user.Status = users.StatusDeleted
+	user.DateUpdated = dates.GetNowDBFormat()
+	return usersdb.WithTransaction(ctx, func(tx usersdb.Executor) *errors.RestErr {
+		if err := user.UpdateStatus(ctx, tx); err != nil {
+			return err
+		}
+		if err := users.NewVersion(*user).Save(ctx, tx); err != nil {
+			return err
+		}
+		entry := audits.NewEntry(ctx, audits.ActionDelete, user.ID, before, user.Marshal(false))
+		return entry.Save(ctx, tx)
+	})
+}
+
+// checkNotDeleted returns a conflict error for a deleted user, its profile cannot be changed anymore
+func checkNotDeleted(user *users.User) *errors.RestErr {
+	if user.Status != users.StatusDeleted {
+		return nil
+	}
+	return errors.NewConflictError(errors.CodeUserDeleted, fmt.Sprintf("user %d is deleted", user.ID)).WithParam("user_id", user.ID)
+}
+
+// newStatusTransitionDeniedError is returned when the user cannot move from its current status to the requested one
+func newStatusTransitionDeniedError(from string, to string) *errors.RestErr {
+	return errors.NewConflictError(errors.CodeStatusTransitionDenied, fmt.Sprintf("user cannot change status from %s to %s", from, to)).
+		WithParam("from", from).WithParam("to", to)
+}
+
+// checkVersion returns a precondition failed error if the client expects another version than the current one,
+// an expected version of 0 means the client did not ask for a check
+func checkVersion(current *users.User, expectedVersion int64) *errors.RestErr {
+	if expectedVersion != 0 && expectedVersion != current.Version {
+		return errors.NewPreconditionFailedError(errors.CodeVersionMismatch, fmt.Sprintf("user is at version %d, not %d", current.Version, expectedVersion)).
+			WithMessageKey("VERSION_MISMATCH.expected").WithParam("current", current.Version).WithParam("expected", expectedVersion)
+	}
+	return nil
 }
 
 // Search function is used to find users in database based on status
-func (s *usersService) SearchUser(status string) (users.Users, *errors.RestErr) {
+func (s *usersService) SearchUser(ctx context.Context, status string) (users.Users, *errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "usersService.SearchUser")
+	defer span.End()
+
+	if err := users.ValidateStatus(status); err != nil {
+		return nil, err
+	}
 	user := &users.User{}
 	// the following code is the same as
-	return user.FindByStatus(status)
+	return user.FindByStatus(ctx, status)
 
 	// userSlice, err := user.FindByStatus(status)
 	// if err != nil {
@@ -98,13 +275,77 @@ func (s *usersService) SearchUser(status string) (users.Users, *errors.RestErr)
 	// return userSlice, nil
 }
 
+// ChangeUserStatus function is used to move a user from the "from" status to the "to" status, only allowed transitions are accepted
+func (s *usersService) ChangeUserStatus(ctx context.Context, userID int64, from string, to string, request users.StatusChangeRequest) (*users.User, *errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "usersService.ChangeUserStatus")
+	defer span.End()
+
+	if err := users.ValidateStatus(to); err != nil {
+		return nil, err
+	}
+	if err := request.Validate(to); err != nil {
+		return nil, err
+	}
+
+	current := &users.User{ID: userID}
+	if err := current.Get(ctx); err != nil {
+		return nil, err
+	}
+	// e.g. unlock only works on a locked user, it must not reactivate a suspended one
+	if current.Status != from || !users.CanTransition(current.Status, to) {
+		return nil, newStatusTransitionDeniedError(current.Status, to)
+	}
+
+	before := current.Marshal(false)
+	previous := current.Status
+	current.Status = to
+	current.DateUpdated = dates.GetNowDBFormat()
+	err := usersdb.WithTransaction(ctx, func(tx usersdb.Executor) *errors.RestErr {
+		if err := current.UpdateStatus(ctx, tx); err != nil {
+			return err
+		}
+		if err := users.NewVersion(*current).Save(ctx, tx); err != nil {
+			return err
+		}
+		entry := audits.NewEntry(ctx, audits.ActionStatusChange, current.ID, before, current.Marshal(false))
+		entry.Reason = request.Reason
+		return entry.Save(ctx, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.InfoContext(ctx, "user status changed",
+		zap.Int64("user_id", current.ID),
+		zap.String("from", previous),
+		zap.String("to", to),
+		zap.String("reason", request.Reason))
+	return current, nil
+}
+
 // LoginUser is use to find user by email and password in database, then create access token
-func (s *usersService) LoginUser(request users.LoginRequest) (*users.User, *errors.RestErr) {
+func (s *usersService) LoginUser(ctx context.Context, request users.LoginRequest) (*users.User, *errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "usersService.LoginUser")
+	defer span.End()
+
+	user, err := loginUser(ctx, request)
+	// the error code is the reason of the failure, e.g. INVALID_CREDENTIALS or VALIDATION_FAILED
+	if err != nil {
+		metrics.ObserveLogin(metrics.LoginFailure, err.Code)
+		return nil, err
+	}
+	metrics.ObserveLogin(metrics.LoginSuccess, "")
+	return user, nil
+}
+
+func loginUser(ctx context.Context, request users.LoginRequest) (*users.User, *errors.RestErr) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
 	user := &users.User{
 		Email:    request.Email,
 		Password: cryptos.GetMd5(request.Password),
 	}
-	if err := user.FindByEmailAndPassword(); err != nil {
+	if err := user.FindByEmailAndPassword(ctx); err != nil {
 		return nil, err
 	}
 	return user, nil
@@ -21,3 +21,22 @@ func GetNowString() string {
 func GetNowDBFormat() string {
 	return GetNow().Format(apiDbLayout)
 }
+
+// ParseAPIDate is to parse a datetime given by the client, e.g. "2020-01-02T15:04:05Z" or with an offset
+func ParseAPIDate(value string) (time.Time, error) {
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return date.UTC(), nil
+}
+
+// ToDBFormat is to format the given time in the datetime format used in the database
+func ToDBFormat(date time.Time) string {
+	return date.UTC().Format(apiDbLayout)
+}
+
+// ParseDBDate is to parse a datetime read from the database, which is always in UTC
+func ParseDBDate(value string) (time.Time, error) {
+	return time.ParseInLocation(apiDbLayout, value, time.UTC)
+}
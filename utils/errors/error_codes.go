@@ -0,0 +1,72 @@
+package errors
+
+// these are the stable error codes of the api, clients can branch on them (and localize messages) instead of parsing messages,
+// a code is never renamed or reused for another meaning once it is released
+const (
+	CodeInvalidJSON              = "INVALID_JSON"
+	CodeValidationFailed         = "VALIDATION_FAILED"
+	CodeInvalidUserID            = "INVALID_USER_ID"
+	CodeInvalidParameter         = "INVALID_PARAMETER"
+	CodeInvalidStatus            = "INVALID_STATUS"
+	CodeReasonRequired           = "REASON_REQUIRED"
+	CodeStatusTransitionDenied   = "STATUS_TRANSITION_NOT_ALLOWED"
+	CodeUserDeleted              = "USER_DELETED"
+	CodeInvalidPatch             = "INVALID_PATCH"
+	CodeInvalidIdempotencyKey    = "INVALID_IDEMPOTENCY_KEY"
+	CodeUnauthorized             = "UNAUTHORIZED"
+	CodeUnsupportedMediaType     = "UNSUPPORTED_MEDIA_TYPE"
+	CodeUserNotFound             = "USER_NOT_FOUND"
+	CodeUserVersionNotFound      = "USER_VERSION_NOT_FOUND"
+	CodeNoUsersFound             = "NO_USERS_FOUND"
+	CodeInvalidCredentials       = "INVALID_CREDENTIALS"
+	CodeEmailTaken               = "EMAIL_TAKEN"
+	CodePatchTestFailed          = "PATCH_TEST_FAILED"
+	CodeIdempotencyKeyInProgress = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	CodeVersionMismatch          = "VERSION_MISMATCH"
+	CodeIdempotencyKeyReused     = "IDEMPOTENCY_KEY_REUSED"
+	CodeIfMatchRequired          = "IF_MATCH_REQUIRED"
+	CodeDatabaseError            = "DATABASE_ERROR"
+	CodeInternalError            = "INTERNAL_ERROR"
+)
+
+// CodeInfo describes one error code of the catalog
+type CodeInfo struct {
+	Code        string `json:"code"`
+	Status      int    `json:"status"`
+	Description string `json:"description"`
+}
+
+// codes is the catalog of every error code, it is listed by GET /errors/codes
+var codes = []CodeInfo{
+	{CodeInvalidJSON, 400, "the request body is not valid json"},
+	{CodeValidationFailed, 400, "one or more fields are invalid, every field is listed in causes"},
+	{CodeInvalidUserID, 400, "the user id in the url is not a number"},
+	{CodeInvalidParameter, 400, "a query parameter is missing or invalid"},
+	{CodeInvalidStatus, 400, "the status is not one of the user statuses"},
+	{CodeReasonRequired, 400, "a reason is required to move the user to this status"},
+	{CodeInvalidPatch, 400, "the patch document is invalid or changes a field that cannot be changed"},
+	{CodeInvalidIdempotencyKey, 400, "the Idempotency-Key header is invalid"},
+	{CodeUnauthorized, 401, "the Authorization header is missing or its token is wrong"},
+	{CodeUnsupportedMediaType, 415, "the Content-Type of the request body is not supported by the endpoint"},
+	{CodeUserNotFound, 404, "there is no user with this id"},
+	{CodeUserVersionNotFound, 404, "the user has no revision (at the requested time)"},
+	{CodeNoUsersFound, 404, "no user matches the search"},
+	{CodeInvalidCredentials, 404, "the email and password do not match an active user"},
+	{CodeEmailTaken, 409, "another user already has this email"},
+	{CodeUserDeleted, 409, "the user is deleted, so it cannot be changed anymore"},
+	{CodeStatusTransitionDenied, 409, "the user cannot move from its current status to the requested one"},
+	{CodePatchTestFailed, 409, "a test operation of the json patch failed, nothing was changed"},
+	{CodeIdempotencyKeyInProgress, 409, "a request with this Idempotency-Key is still being handled"},
+	{CodeVersionMismatch, 412, "the user has changed since the version given in If-Match"},
+	{CodeIdempotencyKeyReused, 422, "the Idempotency-Key was already used with a different request"},
+	{CodeIfMatchRequired, 428, "the If-Match header is required for this request"},
+	{CodeDatabaseError, 500, "the database could not handle the request"},
+	{CodeInternalError, 500, "an unexpected error happened"},
+}
+
+// GetCodes returns the whole catalog of error codes
+func GetCodes() []CodeInfo {
+	result := make([]CodeInfo, len(codes))
+	copy(result, codes)
+	return result
+}
@@ -0,0 +1,57 @@
+package errors
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/annazhao/bookstore_users_api/utils/locales"
+)
+
+func TestGetCodes(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, info := range GetCodes() {
+		if seen[info.Code] {
+			t.Errorf("%s is in the catalog twice", info.Code)
+		}
+		seen[info.Code] = true
+
+		if http.StatusText(info.Status) == "" || info.Status < 400 {
+			t.Errorf("%s has status %d, want an http error status", info.Code, info.Status)
+		}
+		if info.Description == "" {
+			t.Errorf("%s has no description", info.Code)
+		}
+		if _, _, ok := locales.Translate([]string{locales.DefaultLocale}, info.Code, nil); !ok {
+			t.Errorf("%s has no %s message", info.Code, locales.DefaultLocale)
+		}
+	}
+}
+
+// the status of each constructor must be the one listed in the catalog for the codes it is used with
+func TestCodeStatus(t *testing.T) {
+	catalog := make(map[string]int)
+	for _, info := range GetCodes() {
+		catalog[info.Code] = info.Status
+	}
+	tests := []*RestErr{
+		NewBadRequestError(CodeInvalidJSON, ""),
+		NewValidationError(nil),
+		NewUnauthorizedError(CodeUnauthorized, ""),
+		NewUnsupportedMediaTypeError(CodeUnsupportedMediaType, ""),
+		NewNotFoundError(CodeUserNotFound, ""),
+		NewConflictError(CodeStatusTransitionDenied, ""),
+		NewConflictError(CodeEmailTaken, ""),
+		NewPreconditionFailedError(CodeVersionMismatch, ""),
+		NewUnprocessableEntityError(CodeIdempotencyKeyReused, ""),
+		NewPreconditionRequiredError(CodeIfMatchRequired, ""),
+		NewInternalServerError(CodeDatabaseError, ""),
+	}
+	for _, restErr := range tests {
+		if want, ok := catalog[restErr.Code]; !ok || restErr.Status != want {
+			t.Errorf("%s has status %d, want %d", restErr.Code, restErr.Status, want)
+		}
+		if _, ok := problemTypes[restErr.ErrorType]; !ok {
+			t.Errorf("%s has error type %s, which is not a problem type", restErr.Code, restErr.ErrorType)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package errors
+
+// ProblemContentType is the content type of an RFC 7807 problem details response
+const ProblemContentType = "application/problem+json"
+
+// ProblemType is one entry of the catalog of error types, its URI identifies the type of problem
+type ProblemType struct {
+	URI   string `json:"type"`
+	Title string `json:"title"`
+}
+
+// problemTypes is the catalog of every type of problem the api returns, keyed by RestErr.ErrorType,
+// the URIs are relative, so they resolve against the api host
+var problemTypes = map[string]ProblemType{
+	"bad_request":            {URI: "/problems/bad-request", Title: "Bad Request"},
+	"unauthorized":           {URI: "/problems/unauthorized", Title: "Unauthorized"},
+	"not_found":              {URI: "/problems/not-found", Title: "Not Found"},
+	"conflict":               {URI: "/problems/conflict", Title: "Conflict"},
+	"precondition_failed":    {URI: "/problems/precondition-failed", Title: "Precondition Failed"},
+	"precondition_required":  {URI: "/problems/precondition-required", Title: "Precondition Required"},
+	"unsupported_media_type": {URI: "/problems/unsupported-media-type", Title: "Unsupported Media Type"},
+	"unprocessable_entity":   {URI: "/problems/unprocessable-entity", Title: "Unprocessable Entity"},
+	"internal_server_error":  {URI: "/problems/internal-server-error", Title: "Internal Server Error"},
+}
+
+// Problem is the RFC 7807 representation of a RestErr,
+// code, request_id and errors are extension members
+type Problem struct {
+	Type      string  `json:"type"`
+	Title     string  `json:"title"`
+	Status    int     `json:"status"`
+	Detail    string  `json:"detail"`
+	Instance  string  `json:"instance,omitempty"`
+	Code      string  `json:"code,omitempty"`
+	RequestID string  `json:"request_id,omitempty"`
+	Errors    []Cause `json:"errors,omitempty"`
+}
+
+// GetProblemType returns the type of problem for the given RestErr.ErrorType, "about:blank" if it is not in the catalog
+func GetProblemType(errorType string) ProblemType {
+	if problemType, ok := problemTypes[errorType]; ok {
+		return problemType
+	}
+	return ProblemType{URI: "about:blank", Title: errorType}
+}
+
+// Problem method is used to convert the RestErr to a problem details object,
+// instance is the path of the request and requestID its id, both are optional
+func (restErr *RestErr) Problem(instance string, requestID string) Problem {
+	problemType := GetProblemType(restErr.ErrorType)
+	return Problem{
+		Type:      problemType.URI,
+		Title:     problemType.Title,
+		Status:    restErr.Status,
+		Detail:    restErr.Message,
+		Instance:  instance,
+		Code:      restErr.Code,
+		RequestID: requestID,
+		Errors:    restErr.Causes,
+	}
+}
@@ -0,0 +1,43 @@
+package errors
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestProblem(t *testing.T) {
+	causes := []Cause{
+		{Field: "email", Code: "invalid_format", Message: "email is not a valid email address"},
+		{Field: "first_name", Code: "too_long", Message: "first_name must be at most 45 characters", Params: map[string]string{"max": "45"}},
+	}
+	restErr := NewValidationError(causes)
+
+	got := restErr.Problem("/users", "abc")
+	want := Problem{
+		Type:      "/problems/bad-request",
+		Title:     "Bad Request",
+		Status:    http.StatusBadRequest,
+		Detail:    "invalid request",
+		Instance:  "/users",
+		Code:      CodeValidationFailed,
+		RequestID: "abc",
+		Errors:    causes,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Problem() = %+v, want %+v", got, want)
+	}
+}
+
+func TestProblemWithoutCauses(t *testing.T) {
+	got := NewNotFoundError(CodeUserNotFound, "user 1 not found").Problem("", "")
+	if got.Type != "/problems/not-found" || got.Status != http.StatusNotFound || got.Code != CodeUserNotFound || got.Errors != nil {
+		t.Errorf("Problem() = %+v, want a not found problem without errors", got)
+	}
+}
+
+func TestGetProblemType(t *testing.T) {
+	if got := GetProblemType("teapot"); got.URI != "about:blank" || got.Title != "teapot" {
+		t.Errorf("GetProblemType(teapot) = %+v, want about:blank", got)
+	}
+}
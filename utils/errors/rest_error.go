@@ -1,14 +1,81 @@
 package errors
 
 import (
+	"fmt"
 	"net/http"
 )
 
 // RestErr is a comman error struct that will be used in all apis
+// Code is a stable machine-readable code from the catalog in error_codes.go, e.g. EMAIL_TAKEN
+// Causes is only set for validation errors, with one cause for each invalid field
+// MessageKey and Params are used to translate the message, they are never sent to the client
+// RequestID is the id of the request which failed, it is set when the error is sent so support can find its logs
+// cause is the underlying error (e.g. from the database driver), it is logged but never sent to the client
 type RestErr struct {
-	Message string `json:"message"`
-	Status  int    `json:"status"`
-	Error   string `json:"error"`
+	Message    string            `json:"message"`
+	Status     int               `json:"status"`
+	ErrorType  string            `json:"error"`
+	Code       string            `json:"code"`
+	Causes     []Cause           `json:"causes,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	MessageKey string            `json:"-"`
+	Params     map[string]string `json:"-"`
+	cause      error
+}
+
+// Cause is one problem found while validating a request, e.g. {"field": "email", "code": "invalid_format"}
+// Params are used to translate the message, e.g. {"max": "45"}
+type Cause struct {
+	Field   string            `json:"field"`
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Params  map[string]string `json:"-"`
+}
+
+// Error method makes RestErr an error, so it works with errors.Is and errors.As through Unwrap
+func (restErr *RestErr) Error() string {
+	if restErr.cause == nil {
+		return restErr.Message
+	}
+	return fmt.Sprintf("%s: %v", restErr.Message, restErr.cause)
+}
+
+// Unwrap method returns the underlying cause, or nil if there is none
+func (restErr *RestErr) Unwrap() error {
+	return restErr.cause
+}
+
+// Wrap method is used to keep the underlying error with what we were trying to do when it happened,
+// e.g. Wrap("error when trying to get user by id", err)
+func (restErr *RestErr) Wrap(context string, cause error) *RestErr {
+	if cause != nil {
+		restErr.cause = fmt.Errorf("%s: %w", context, cause)
+	}
+	return restErr
+}
+
+// WithMessageKey method is used when the message is not the default one of the code,
+// the key is then the template used to translate it, e.g. "INVALID_PARAMETER.limit"
+func (restErr *RestErr) WithMessageKey(key string) *RestErr {
+	restErr.MessageKey = key
+	return restErr
+}
+
+// WithParam method is used to give a value used in the message template, e.g. {user_id}
+func (restErr *RestErr) WithParam(name string, value interface{}) *RestErr {
+	if restErr.Params == nil {
+		restErr.Params = make(map[string]string)
+	}
+	restErr.Params[name] = fmt.Sprint(value)
+	return restErr
+}
+
+// GetMessageKey returns the key of the template used to translate the message, it is the code unless another key was set
+func (restErr *RestErr) GetMessageKey() string {
+	if restErr.MessageKey != "" {
+		return restErr.MessageKey
+	}
+	return restErr.Code
 }
 
 // func NewError(msg string) error {
@@ -16,28 +83,102 @@ type RestErr struct {
 // }
 
 // NewBadRequestError is a function to create new bad request error
-func NewBadRequestError(message string) *RestErr {
+func NewBadRequestError(code string, message string) *RestErr {
+	return &RestErr{
+		Code:      code,
+		Message:   message,
+		Status:    http.StatusBadRequest,
+		ErrorType: "bad_request",
+	}
+}
+
+// NewValidationError is a function to create new bad request error listing every invalid field
+func NewValidationError(causes []Cause) *RestErr {
+	return &RestErr{
+		Code:      CodeValidationFailed,
+		Message:   "invalid request",
+		Status:    http.StatusBadRequest,
+		ErrorType: "bad_request",
+		Causes:    causes,
+	}
+}
+
+// NewUnauthorizedError is a function to create new unauthorized error, e.g. when the admin token is missing or wrong
+func NewUnauthorizedError(code string, message string) *RestErr {
 	return &RestErr{
-		Message: message,
-		Status:  http.StatusBadRequest,
-		Error:   "bad_request",
+		Code:      code,
+		Message:   message,
+		Status:    http.StatusUnauthorized,
+		ErrorType: "unauthorized",
 	}
 }
 
 // NewNotFoundError is a function to create new not found error
-func NewNotFoundError(message string) *RestErr {
+func NewNotFoundError(code string, message string) *RestErr {
 	return &RestErr{
-		Message: message,
-		Status:  http.StatusNotFound,
-		Error:   "not_found",
+		Code:      code,
+		Message:   message,
+		Status:    http.StatusNotFound,
+		ErrorType: "not_found",
 	}
 }
 
 // NewInternalServerError is a function to create new internal server error
-func NewInternalServerError(message string) *RestErr {
+func NewInternalServerError(code string, message string) *RestErr {
+	return &RestErr{
+		Code:      code,
+		Message:   message,
+		Status:    http.StatusInternalServerError,
+		ErrorType: "internal_server_error",
+	}
+}
+
+// NewPreconditionFailedError is a function to create new precondition failed error, e.g. when If-Match does not match anymore
+func NewPreconditionFailedError(code string, message string) *RestErr {
+	return &RestErr{
+		Code:      code,
+		Message:   message,
+		Status:    http.StatusPreconditionFailed,
+		ErrorType: "precondition_failed",
+	}
+}
+
+// NewPreconditionRequiredError is a function to create new precondition required error, e.g. when If-Match is missing
+func NewPreconditionRequiredError(code string, message string) *RestErr {
+	return &RestErr{
+		Code:      code,
+		Message:   message,
+		Status:    http.StatusPreconditionRequired,
+		ErrorType: "precondition_required",
+	}
+}
+
+// NewConflictError is a function to create new conflict error, e.g. when the request conflicts with the current state of the resource
+func NewConflictError(code string, message string) *RestErr {
+	return &RestErr{
+		Code:      code,
+		Message:   message,
+		Status:    http.StatusConflict,
+		ErrorType: "conflict",
+	}
+}
+
+// NewUnsupportedMediaTypeError is a function to create new unsupported media type error, e.g. when a patch has an unknown Content-Type
+func NewUnsupportedMediaTypeError(code string, message string) *RestErr {
+	return &RestErr{
+		Code:      code,
+		Message:   message,
+		Status:    http.StatusUnsupportedMediaType,
+		ErrorType: "unsupported_media_type",
+	}
+}
+
+// NewUnprocessableEntityError is a function to create new unprocessable entity error, e.g. when an idempotency key is reused with another body
+func NewUnprocessableEntityError(code string, message string) *RestErr {
 	return &RestErr{
-		Message: message,
-		Status:  http.StatusInternalServerError,
-		Error:   "internal_server_error",
+		Code:      code,
+		Message:   message,
+		Status:    http.StatusUnprocessableEntity,
+		ErrorType: "unprocessable_entity",
 	}
 }
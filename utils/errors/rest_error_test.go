@@ -0,0 +1,57 @@
+package errors
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"strings"
+	"testing"
+)
+
+var errDriver = stderrors.New("connection refused")
+
+func TestWrap(t *testing.T) {
+	restErr := NewInternalServerError(CodeDatabaseError, "database error").Wrap("error when trying to get user by id", errDriver)
+
+	if !stderrors.Is(restErr, errDriver) {
+		t.Errorf("errors.Is(restErr, errDriver) = false, want true")
+	}
+	if restErr.Unwrap() == nil {
+		t.Fatalf("Unwrap() = nil, want the wrapped error")
+	}
+	if want := "database error: error when trying to get user by id: connection refused"; restErr.Error() != want {
+		t.Errorf("Error() = %q, want %q", restErr.Error(), want)
+	}
+
+	// a RestErr returned as an error can be found again with errors.As
+	var err error = restErr
+	var target *RestErr
+	if !stderrors.As(err, &target) || target.Code != CodeDatabaseError {
+		t.Errorf("errors.As(err, *RestErr) = %v, want code %s", target, CodeDatabaseError)
+	}
+}
+
+func TestWrapNil(t *testing.T) {
+	restErr := NewInternalServerError(CodeDatabaseError, "database error").Wrap("error when trying to get user by id", nil)
+
+	if restErr.Unwrap() != nil {
+		t.Errorf("Unwrap() = %v, want nil", restErr.Unwrap())
+	}
+	if restErr.Error() != "database error" {
+		t.Errorf("Error() = %q, want %q", restErr.Error(), "database error")
+	}
+}
+
+// the cause can have sql or hosts in it, it must only be logged
+func TestCauseNotInJSON(t *testing.T) {
+	restErr := NewInternalServerError(CodeDatabaseError, "database error").Wrap("error when trying to get user by id", errDriver)
+
+	for _, value := range []interface{}{restErr, restErr.Problem("/users/1", "abc")} {
+		body, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+		if strings.Contains(string(body), "connection refused") || strings.Contains(string(body), "error when trying") {
+			t.Errorf("json = %s, want no cause", body)
+		}
+	}
+}
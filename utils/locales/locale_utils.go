@@ -0,0 +1,105 @@
+package locales
+
+import (
+	"embed"
+	"encoding/json"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is the last locale of every fallback chain, every message must exist in it
+const DefaultLocale = "en"
+
+//go:embed messages/*.json
+var messageFiles embed.FS // embedded in the binary, one file per locale, e.g. messages/fr.json
+
+// messages holds the templates of every locale, keyed by locale then by message key (an error code or "cause.<code>")
+var messages = loadMessages()
+
+func loadMessages() map[string]map[string]string {
+	files, err := messageFiles.ReadDir("messages")
+	if err != nil {
+		panic(err)
+	}
+
+	result := make(map[string]map[string]string)
+	for _, file := range files {
+		content, err := messageFiles.ReadFile(path.Join("messages", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var templates map[string]string
+		// the files are embedded, so a broken one can only come from a bad build
+		if err := json.Unmarshal(content, &templates); err != nil {
+			panic(err)
+		}
+		result[strings.TrimSuffix(file.Name(), ".json")] = templates
+	}
+	return result
+}
+
+// Negotiate returns the fallback chain of locales for an Accept-Language header, best first,
+// e.g. "fr-CA,es;q=0.5" gives [fr-ca fr es en]
+func Negotiate(acceptLanguage string) []string {
+	type languageRange struct {
+		tag     string
+		quality float64
+	}
+	ranges := make([]languageRange, 0)
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		params := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(params[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				quality, _ = strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, languageRange{tag: tag, quality: quality})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	chain := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(locale string) {
+		if !seen[locale] {
+			seen[locale] = true
+			chain = append(chain, locale)
+		}
+	}
+	for _, languageRange := range ranges {
+		add(languageRange.tag)
+		// fr-ca falls back to fr
+		if index := strings.Index(languageRange.tag, "-"); index > 0 {
+			add(languageRange.tag[:index])
+		}
+	}
+	add(DefaultLocale)
+	return chain
+}
+
+// Translate returns the message for the key in the first locale of the chain that has it, with its params filled in,
+// it also returns that locale, or false if no locale of the chain has the key
+func Translate(chain []string, key string, params map[string]string) (string, string, bool) {
+	for _, locale := range chain {
+		template, ok := messages[locale][key]
+		if !ok {
+			continue
+		}
+		for name, value := range params {
+			template = strings.Replace(template, "{"+name+"}", value, -1)
+		}
+		return template, locale, true
+	}
+	return "", "", false
+}
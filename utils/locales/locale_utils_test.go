@@ -0,0 +1,112 @@
+package locales
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		want           []string
+	}{
+		{"", []string{"en"}},
+		{"fr", []string{"fr", "en"}},
+		{"FR-ca", []string{"fr-ca", "fr", "en"}},
+		{"fr-CA,es;q=0.5", []string{"fr-ca", "fr", "es", "en"}},
+		{"es;q=0.5, fr;q=0.8", []string{"fr", "es", "en"}},
+		{"es;q=0, fr", []string{"fr", "en"}},
+		{"*, de", []string{"de", "en"}},
+		{"en-GB, en", []string{"en-gb", "en"}},
+		{"fr, es, fr", []string{"fr", "es", "en"}},
+	}
+	for _, test := range tests {
+		if got := Negotiate(test.acceptLanguage); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Negotiate(%q) = %v, want %v", test.acceptLanguage, got, test.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name       string
+		chain      []string
+		key        string
+		params     map[string]string
+		want       string
+		wantLocale string
+		wantOK     bool
+	}{
+		{
+			name:       "first locale of the chain",
+			chain:      []string{"fr", "en"},
+			key:        "USER_NOT_FOUND",
+			params:     map[string]string{"user_id": "7"},
+			want:       "utilisateur 7 introuvable",
+			wantLocale: "fr",
+			wantOK:     true,
+		},
+		{
+			name:       "falls back to the next locale",
+			chain:      []string{"de", "en"},
+			key:        "USER_NOT_FOUND",
+			params:     map[string]string{"user_id": "7"},
+			want:       "user 7 not found",
+			wantLocale: "en",
+			wantOK:     true,
+		},
+		{
+			name:       "every param is replaced",
+			chain:      []string{"en"},
+			key:        "STATUS_TRANSITION_NOT_ALLOWED",
+			params:     map[string]string{"from": "locked", "to": "suspended"},
+			want:       "user cannot change status from locked to suspended",
+			wantLocale: "en",
+			wantOK:     true,
+		},
+		{
+			name:  "unknown key",
+			chain: []string{"fr", "en"},
+			key:   "NOT_A_KEY",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, locale, ok := Translate(test.chain, test.key, test.params)
+			if got != test.want || locale != test.wantLocale || ok != test.wantOK {
+				t.Errorf("Translate() = (%q, %q, %v), want (%q, %q, %v)", got, locale, ok, test.want, test.wantLocale, test.wantOK)
+			}
+		})
+	}
+}
+
+var placeholder = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// every locale must have the same keys as the default one, with the same placeholders
+func TestMessagesMatchDefaultLocale(t *testing.T) {
+	for locale, templates := range messages {
+		for key, template := range templates {
+			defaultTemplate, ok := messages[DefaultLocale][key]
+			if !ok {
+				t.Errorf("%s: %s is not in %s", locale, key, DefaultLocale)
+				continue
+			}
+			if got, want := placeholders(template), placeholders(defaultTemplate); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: %s has placeholders %v, want %v", locale, key, got, want)
+			}
+		}
+		for key := range messages[DefaultLocale] {
+			if _, ok := templates[key]; !ok {
+				t.Errorf("%s: %s is missing", locale, key)
+			}
+		}
+	}
+}
+
+func placeholders(template string) []string {
+	found := placeholder.FindAllString(template, -1)
+	sort.Strings(found)
+	return found
+}
@@ -1,27 +1,23 @@
 package mysqls
 
 import (
-	"strings"
+	"database/sql"
+	"errors"
 
-	"github.com/annazhao/bookstore_users_api/utils/errors"
 	"github.com/go-sql-driver/mysql"
 )
 
-const ErrorNoRows = "no rows in result set"
+// errorDuplicateEntry is the mysql error number when a unique key already has the value
+const errorDuplicateEntry = 1062
 
-// ParseError is used to handle errors related to mysql database process
-func ParseError(err error) *errors.RestErr {
-	sqlErr, ok := err.(*mysql.MySQLError)
-	if !ok {
-		if strings.Contains(err.Error(), ErrorNoRows) {
-			return errors.NewNotFoundError("no record matching given id")
-		}
-		return errors.NewInternalServerError("error parsing database response")
-	}
+// IsDuplicateEntry returns true if err is mysql telling us a unique key already has the value we tried to insert,
+// err can be wrapped, e.g. by fmt.Errorf("...: %w", err)
+func IsDuplicateEntry(err error) bool {
+	var sqlErr *mysql.MySQLError
+	return errors.As(err, &sqlErr) && sqlErr.Number == errorDuplicateEntry
+}
 
-	switch sqlErr.Number {
-	case 1062:
-		return errors.NewBadRequestError("invalid data")
-	}
-	return errors.NewInternalServerError("error processing request")
+// IsNoRows returns true if err means the query did not find any row, e.g. from QueryRow().Scan()
+func IsNoRows(err error) bool {
+	return errors.Is(err, sql.ErrNoRows)
 }
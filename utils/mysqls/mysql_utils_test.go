@@ -0,0 +1,30 @@
+package mysqls
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestIsDuplicateEntry(t *testing.T) {
+	duplicate := &mysql.MySQLError{Number: errorDuplicateEntry, Message: "Duplicate entry 'ann@example.com' for key 'email'"}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"duplicate entry", duplicate, true},
+		{"wrapped duplicate entry", fmt.Errorf("error when trying to save user: %w", duplicate), true},
+		{"another mysql error", &mysql.MySQLError{Number: 1064}, false},
+		{"not a mysql error", fmt.Errorf("connection refused"), false},
+		{"nil", nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := IsDuplicateEntry(test.err); got != test.want {
+				t.Errorf("IsDuplicateEntry() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package responses
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/annazhao/bookstore_users_api/logger"
+	"github.com/annazhao/bookstore_users_api/tracing"
+	"github.com/annazhao/bookstore_users_api/utils/errors"
+	"github.com/annazhao/bookstore_users_api/utils/locales"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Error is used to send the error back to the client, every controller and middleware should use it,
+// the message is translated to the Accept-Language of the request,
+// clients asking for application/problem+json get an RFC 7807 problem, the others get the RestErr as it is
+func Error(c *gin.Context, restErr *errors.RestErr) {
+	logError(c, restErr)
+	restErr = localize(c, restErr)
+	restErr.RequestID = c.GetHeader("X-Request-Id")
+	if !acceptsProblem(c.GetHeader("Accept")) {
+		c.JSON(restErr.Status, restErr)
+		return
+	}
+
+	problem := restErr.Problem(c.Request.URL.Path, restErr.RequestID)
+	problemJSON, err := json.Marshal(problem)
+	if err != nil {
+		c.JSON(restErr.Status, restErr)
+		return
+	}
+	c.Data(restErr.Status, errors.ProblemContentType, problemJSON)
+}
+
+// logError is used to log server errors with their underlying cause, which is never sent to the client,
+// the span of the request is marked as failed too
+func logError(c *gin.Context, restErr *errors.RestErr) {
+	if restErr.Status < http.StatusInternalServerError {
+		return
+	}
+	tracing.RecordError(c.Request.Context(), restErr)
+	// the request id, route and trace id come with the context of the request
+	logger.ErrorContext(c.Request.Context(), restErr.Message, restErr.Unwrap(),
+		zap.String("code", restErr.Code),
+		zap.Int("status", restErr.Status),
+		zap.String("method", c.Request.Method),
+		zap.String("path", c.Request.URL.Path))
+}
+
+// AbortWithError is the same as Error, but also stops the next handlers, it is used by middlewares
+func AbortWithError(c *gin.Context, restErr *errors.RestErr) {
+	Error(c, restErr)
+	c.Abort()
+}
+
+// acceptsProblem returns true if the Accept header lists application/problem+json with a quality above 0
+func acceptsProblem(accept string) bool {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		params := strings.Split(mediaRange, ";")
+		if strings.TrimSpace(params[0]) != errors.ProblemContentType {
+			continue
+		}
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				quality, _ = strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			}
+		}
+		return quality > 0
+	}
+	return false
+}
+
+// localize returns a copy of the error with its message and causes translated,
+// messages without a template in any locale of the chain are kept as they are
+func localize(c *gin.Context, restErr *errors.RestErr) *errors.RestErr {
+	chain := locales.Negotiate(c.GetHeader("Accept-Language"))
+	localized := *restErr
+
+	if message, locale, ok := locales.Translate(chain, restErr.GetMessageKey(), restErr.Params); ok {
+		localized.Message = message
+		c.Header("Content-Language", locale)
+	}
+
+	if len(restErr.Causes) > 0 {
+		localized.Causes = make([]errors.Cause, len(restErr.Causes))
+		for index, cause := range restErr.Causes {
+			if message, _, ok := locales.Translate(chain, "cause."+cause.Code, cause.Params); ok {
+				cause.Message = message
+			}
+			localized.Causes[index] = cause
+		}
+	}
+	return &localized
+}
@@ -0,0 +1,27 @@
+package responses
+
+import "testing"
+
+func TestAcceptsProblem(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"application/json", false},
+		{"*/*", false},
+		{"application/problem+json", true},
+		{"application/problem+json;q=0.5", true},
+		{"application/problem+json; q=0", false},
+		{"application/problem+json;q=0.0", false},
+		{"application/json, application/problem+json", true},
+		{"application/json;q=0.9, application/problem+json;q=0.1", true},
+		{"text/html, application/problem+json;q=0, */*", false},
+		{"application/problem+xml", false},
+	}
+	for _, test := range tests {
+		if got := acceptsProblem(test.accept); got != test.want {
+			t.Errorf("acceptsProblem(%q) = %v, want %v", test.accept, got, test.want)
+		}
+	}
+}